@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"yolotools/dataset"
+)
+
+// ==================== 命令行模式 ====================
+
+// 退出码
+const (
+	exitOK      = 0
+	exitFailed  = 1 // 运行失败或校验未通过
+	exitUsage   = 2 // 参数错误
+	cliProgStep = 5 // 进度输出间隔 (百分比)
+)
+
+const cliUsage = `用法: yolotools <命令> [参数]
+
+命令:
+  build     扫描数据源、划分并生成 YOLO 数据集 (与界面的 [开始执行] 相同)
+  watch     先增量同步一次，之后监视数据源，图片或 JSON 变化时自动增量重建 (Ctrl+C 退出)
+  validate  按 manifest.json 校验数据集目录
+  stats     统计数据源中的图片、标注与各类别数量
+  export    把数据集目录打包为 .zip 或 .tar.gz
+
+各命令的参数见 yolotools <命令> -h。图形界面为单独的 YoloTools 程序
+`
+
+// stringsFlag 可重复的字符串参数
+type stringsFlag []string
+
+func (f *stringsFlag) String() string     { return strings.Join(*f, ",") }
+func (f *stringsFlag) Set(v string) error { *f = append(*f, v); return nil }
+
+// runCLI 执行子命令，返回进程退出码
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, cliUsage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "build":
+		return cliBuild(args[1:], stdout, stderr)
+	case "watch":
+		return cliWatch(args[1:], stdout, stderr)
+	case "validate":
+		return cliValidate(args[1:], stdout, stderr)
+	case "stats":
+		return cliStats(args[1:], stdout, stderr)
+	case "export":
+		return cliExport(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "未知命令: %s\n\n%s", args[0], cliUsage)
+	return exitUsage
+}
+
+// parseSettingsFlags 解析构建设置：先读 -config 指定的文件，再用命令行上显式给出的参数覆盖
+func parseSettingsFlags(flags *flag.FlagSet, args []string) (dataset.Settings, error) {
+	s := dataset.DefaultSettings()
+	var config string
+	var sources stringsFlag
+	flags.StringVar(&config, "config", "", "项目文件 (界面中 [项目 > 保存] 生成的 JSON 或 YAML)，命令行参数优先")
+	flags.Var(&sources, "src", "数据源文件夹，可重复")
+	flags.StringVar(&s.Output, "out", s.Output, "输出目录，或 .zip/.tar.gz")
+	flags.StringVar(&s.Classes, "classes", s.Classes, "类别，逗号分隔")
+	flags.BoolVar(&s.RelativeYaml, "relative-yaml", s.RelativeYaml, "data.yaml 使用相对路径")
+	flags.StringVar(&s.YamlExtra, "yaml-extra", s.YamlExtra, "data.yaml 附加键 (YAML)")
+	flags.StringVar(&s.Train, "train", s.Train, "训练集比例 (0.8)、百分比 (80%) 或张数 (200张 / n=200)")
+	flags.StringVar(&s.Val, "val", s.Val, "验证集比例、百分比或张数，写法同 -train")
+	flags.StringVar(&s.Test, "test", s.Test, "测试集比例、百分比或张数，写法同 -train；留空为剩余全部")
+	flags.StringVar(&s.Seed, "seed", s.Seed, "随机种子，留空随机生成")
+	flags.BoolVar(&s.Stratify, "stratify", s.Stratify, "按类别分层划分")
+	flags.IntVar(&s.Folds, "folds", s.Folds, "K 折数，0 为关闭")
+	flags.BoolVar(&s.Incremental, "incremental", s.Incremental, "增量更新")
+	flags.StringVar(&s.Group, "group", s.Group, "分组方式: "+strings.Join(dataset.GroupModeKeys, " / "))
+	flags.StringVar(&s.GroupRegex, "group-regex", s.GroupRegex, "分组正则 (-group regex)")
+	flags.BoolVar(&s.Process, "process", s.Process, "启用压缩/转格式")
+	flags.IntVar(&s.MaxKB, "max-kb", s.MaxKB, "单张图片大小上限 (KB)")
+	flags.BoolVar(&s.Verbose, "verbose", s.Verbose, "逐张输出压缩结果")
+	flags.StringVar(&s.Resize, "resize", s.Resize, "尺寸调整: "+strings.Join(dataset.ResizeModeKeys, " / "))
+	flags.StringVar(&s.ResizeSize, "resize-size", s.ResizeSize, "目标尺寸，640 或 640x480")
+	flags.StringVar(&s.Pad, "pad", s.Pad, "Letterbox 填充色")
+	flags.StringVar(&s.Alpha, "alpha", s.Alpha, "透明图片: "+strings.Join(dataset.AlphaModeKeys, " / "))
+	flags.StringVar(&s.AlphaBg, "alpha-bg", s.AlphaBg, "透明区域底色")
+	flags.StringVar(&s.TiffPages, "tiff-pages", s.TiffPages, "多页 TIFF: "+strings.Join(dataset.TiffPageModeKeys, " / "))
+	flags.BoolVar(&s.Tile, "tile", s.Tile, "切片")
+	flags.IntVar(&s.TileSize, "tile-size", s.TileSize, "切片边长")
+	flags.IntVar(&s.TileOverlap, "tile-overlap", s.TileOverlap, "切片重叠像素")
+	flags.Float64Var(&s.TileVisible, "tile-visible", s.TileVisible, "切片内框的最小可见比例")
+	flags.BoolVar(&s.Augment, "augment", s.Augment, "离线增强 (仅训练集)")
+	flags.IntVar(&s.AugCopies, "aug-copies", s.AugCopies, "每张图的增强份数")
+	flags.BoolVar(&s.AugHFlip, "aug-hflip", s.AugHFlip, "随机水平翻转")
+	flags.BoolVar(&s.AugVFlip, "aug-vflip", s.AugVFlip, "随机垂直翻转")
+	flags.BoolVar(&s.AugRot90, "aug-rot90", s.AugRot90, "随机 90° 旋转")
+	flags.Float64Var(&s.AugJitter, "aug-jitter", s.AugJitter, "颜色抖动幅度 0~1")
+	flags.Float64Var(&s.AugCrop, "aug-crop", s.AugCrop, "随机裁剪最小比例，0 为不裁剪")
+	flags.BoolVar(&s.Ignore, "ignore", s.Ignore, "启用忽略区域")
+	flags.StringVar(&s.IgnoreLabels, "ignore-labels", s.IgnoreLabels, "忽略区域标签，逗号分隔")
+	flags.StringVar(&s.IgnoreFill, "ignore-fill", s.IgnoreFill, "忽略区域填充色")
+	flags.BoolVar(&s.Redact, "redact", s.Redact, "启用隐私脱敏")
+	flags.StringVar(&s.RedactLabels, "redact-labels", s.RedactLabels, "脱敏标签，逗号分隔")
+	flags.StringVar(&s.RedactMode, "redact-mode", s.RedactMode, "脱敏方式: "+strings.Join(dataset.RedactModeKeys, " / "))
+	flags.BoolVar(&s.RedactDrop, "redact-drop", s.RedactDrop, "从标签中删除脱敏区域")
+	flags.StringVar(&s.RedactFill, "redact-fill", s.RedactFill, "脱敏方式为 fill 时的填充色")
+	flags.StringVar(&s.OutputMode, "mode", s.OutputMode, "输出方式: "+strings.Join(dataset.OutputModeKeys, " / "))
+
+	if err := flags.Parse(args); err != nil {
+		return s, err
+	}
+	if config != "" {
+		loaded, err := dataset.LoadSettingsFile(config)
+		if err != nil {
+			return s, err
+		}
+		// 参数绑定在 s 的字段上，读入配置后再解析一次让命令行覆盖配置
+		s = loaded
+		sources = nil
+		if err := flags.Parse(args); err != nil {
+			return s, err
+		}
+	}
+	if len(sources) > 0 {
+		s.Sources = sources
+	}
+	return s, nil
+}
+
+// cliProgress 每前进 cliProgStep% 输出一行进度
+func cliProgress(w io.Writer) func(done, total int) {
+	var mu sync.Mutex
+	last := -1
+	return func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		pct := done * 100 / total
+		if pct/cliProgStep != last/cliProgStep || done == total {
+			last = pct
+			fmt.Fprintf(w, "[进度] %d/%d (%d%%)\n", done, total, pct)
+		}
+	}
+}
+
+func cliBuild(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	quiet := flags.Bool("quiet", false, "只输出进度与汇总，不输出逐张日志")
+	errReport := flags.String("errors", "", "把失败列表导出到此文件 (.csv 或 .json)")
+	dryRun := flags.Bool("dry-run", false, "只扫描、划分并报告统计与问题，不写入任何文件")
+	s, err := parseSettingsFlags(flags, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	opts, err := s.Options()
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	opts.DryRun = *dryRun
+
+	var mu sync.Mutex
+	logf := func(msg string) {
+		// 逐张压缩日志不以 >>> / !!! 开头，安静模式下略过
+		if *quiet && !strings.HasPrefix(msg, ">>>") && !strings.HasPrefix(msg, "!!!") {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(stdout, msg)
+	}
+	progress := cliProgress(stdout)
+	opts.OnEvent = func(e dataset.Event) {
+		switch e.Kind {
+		case dataset.EventLog:
+			logf(e.Message)
+		case dataset.EventProgress:
+			progress(e.Done, e.Total)
+		}
+	}
+	// Ctrl+C 时停止并删除本次写入的文件
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res, err := dataset.Run(ctx, opts)
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(stderr, "已取消")
+		return exitFailed
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitFailed
+	}
+	if res.Plan != nil {
+		// 安静模式下报告不经过日志，这里补上
+		if *quiet {
+			for _, line := range res.Plan.Lines() {
+				fmt.Fprintln(stdout, line)
+			}
+		}
+		return exitOK
+	}
+	var parts []string
+	for _, name := range dataset.SplitNames {
+		if n, ok := res.Splits[name]; ok {
+			parts = append(parts, fmt.Sprintf("%s %d", name, n))
+		}
+	}
+	if len(parts) == 0 {
+		for name, n := range res.Splits {
+			parts = append(parts, fmt.Sprintf("%s %d", name, n))
+		}
+		sort.Strings(parts)
+	}
+	fmt.Fprintf(stdout, "共 %d 张 (%s)，种子 %d，跳过 %d\n", res.Images, strings.Join(parts, " / "), opts.Seed, res.Skipped)
+	if *errReport != "" {
+		if err := dataset.SaveErrorReport(*errReport, res.Errors); err != nil {
+			fmt.Fprintln(stderr, "失败列表导出失败:", err)
+		}
+	}
+	// 有文件失败时以非零退出，便于脚本发现不完整的数据集
+	if len(res.Errors) > 0 {
+		fmt.Fprintf(stderr, "%d 处失败\n", len(res.Errors))
+		return exitFailed
+	}
+	return exitOK
+}
+
+func cliWatch(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	debounce := flags.Duration("debounce", dataset.DefaultDebounce, "最后一次变化后等待多久再重建")
+	s, err := parseSettingsFlags(flags, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	// 种子要在多次重建间保持一致，这里解析一次后一直沿用
+	opts, err := s.Options()
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	var mu sync.Mutex
+	opts.OnEvent = func(e dataset.Event) {
+		// 逐张日志与进度太多，只输出状态行和错误
+		if e.Kind == dataset.EventStatus || (e.Kind == dataset.EventLog && strings.HasPrefix(e.Message, "!!!")) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintln(stdout, e.Message)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := dataset.Watch(ctx, opts, *debounce); err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+func cliValidate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprintln(stderr, "用法: yolotools validate <数据集目录>") }
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	report, err := dataset.VerifyManifest(flags.Arg(0), nil)
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitFailed
+	}
+	for _, p := range report.Missing {
+		fmt.Fprintln(stdout, "[缺失] "+p)
+	}
+	for _, p := range report.Modified {
+		fmt.Fprintln(stdout, "[已修改] "+p)
+	}
+	for _, p := range report.Extra {
+		fmt.Fprintln(stdout, "[多余] "+p)
+	}
+	fmt.Fprintf(stdout, "已校验 %d 个文件：缺失 %d，已修改 %d，多余 %d\n",
+		report.Checked, len(report.Missing), len(report.Modified), len(report.Extra))
+	if !report.OK() {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func cliStats(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	s, err := parseSettingsFlags(flags, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	if len(s.Sources) == 0 {
+		fmt.Fprintln(stderr, "错误: 未指定数据源 (-src)")
+		return exitUsage
+	}
+	classes, err := dataset.ParseClassList(s.Classes)
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitUsage
+	}
+	clsMap := make(map[string]int)
+	for i, c := range classes {
+		clsMap[c] = i
+	}
+
+	tasks := dataset.ScanSources(s.Sources, func(msg string) { fmt.Fprintln(stderr, msg) })
+	labeled, failed := 0, 0
+	boxCount := make([]int, len(classes))
+	imgCount := make([]int, len(classes))
+	for _, t := range tasks {
+		if _, err := os.Stat(t.JsonPath); err != nil {
+			continue
+		}
+		boxes, err := dataset.LoadLabelMeBoxes(t.JsonPath, clsMap)
+		if err != nil {
+			failed++
+			continue
+		}
+		labeled++
+		seen := make(map[int]bool)
+		for _, b := range boxes {
+			boxCount[b.Cls]++
+			if !seen[b.Cls] {
+				seen[b.Cls] = true
+				imgCount[b.Cls]++
+			}
+		}
+	}
+	fmt.Fprintf(stdout, "图片 %d 张，有标注 %d 张，无标注 %d 张，标注读取失败 %d 张\n", len(tasks), labeled, len(tasks)-labeled-failed, failed)
+	fmt.Fprintf(stdout, "%-4s %-20s %8s %8s\n", "ID", "类别", "框数", "图片数")
+	for i, c := range classes {
+		fmt.Fprintf(stdout, "%-4d %-20s %8d %8d\n", i, c, boxCount[i], imgCount[i])
+	}
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func cliExport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprintln(stderr, "用法: yolotools export <数据集目录> <输出 .zip/.tar.gz>") }
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		flags.Usage()
+		return exitUsage
+	}
+	root, out := flags.Arg(0), flags.Arg(1)
+	if !dataset.IsArchivePath(out) {
+		fmt.Fprintln(stderr, "错误: 输出必须是 .zip、.tar.gz 或 .tgz")
+		return exitUsage
+	}
+	sink, err := dataset.NewDatasetSink(out)
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitFailed
+	}
+	n := 0
+	absOut, _ := filepath.Abs(out)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		// 压缩包本身放在数据集目录里时跳过
+		if abs, _ := filepath.Abs(p); abs == absOut {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		n++
+		return sink.WriteFile(filepath.ToSlash(rel), data)
+	})
+	if cerr := sink.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "错误:", err)
+		return exitFailed
+	}
+	fmt.Fprintf(stdout, "已打包 %d 个文件到 %s\n", n, out)
+	return exitOK
+}
@@ -0,0 +1,8 @@
+// yolotools 是数据集工具的命令行版本，只依赖 dataset 包，不链接图形库，可在无显示器的服务器上运行
+package main
+
+import "os"
+
+func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
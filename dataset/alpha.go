@@ -0,0 +1,67 @@
+package dataset
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// ==================== 透明通道处理 ====================
+
+// AlphaMode 含透明通道的图片 (PNG/GIF 等) 的输出方式
+type AlphaMode int
+
+const (
+	AlphaComposite AlphaMode = iota // 铺在背景色上再转 JPEG
+	AlphaKeepPNG                    // 保留无损 PNG
+)
+
+var AlphaModeNames = []string{"透明区域铺底色转 JPEG", "含透明时保留 PNG"}
+
+// 透明处理方式的英文键名
+var AlphaModeKeys = []string{"composite", "png"}
+
+// AlphaOptions 透明通道处理参数
+type AlphaOptions struct {
+	Mode       AlphaMode
+	Background color.RGBA
+}
+
+// HasAlpha 图片是否含非不透明像素；调色板图片只看实际用到的颜色
+func HasAlpha(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FlattenAlpha 把图片铺在背景色上，得到完全不透明的 RGBA
+func FlattenAlpha(img image.Image, bg color.RGBA) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	bg.A = 255
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// encodePNG 无损 PNG 编码，源文件本身是未改动的 PNG 时直接沿用
+func encodePNG(img image.Image, src []byte, format string) ([]byte, error) {
+	if src != nil && format == "png" {
+		return src, nil
+	}
+	buf := new(bytes.Buffer)
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	err := enc.Encode(buf, img)
+	return buf.Bytes(), err
+}
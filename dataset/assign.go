@@ -0,0 +1,201 @@
+package dataset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ==================== 划分分配 ====================
+
+// SplitPlan 每张源图片所属的划分；增量模式下还带着上次的划分记录
+type SplitPlan struct {
+	Names   []string        // 划分名，K 折时为 fold_0 ...
+	Subsets []string        // Subsets[i] 为 tasks[i] 的划分
+	Assign  *AssignmentFile // 本次运行的划分记录，构建结束后写回 splits.json
+	Reuse   bool            // 处理参数与上次相同，未变化的图片可以沿用上次的输出
+}
+
+// Counts 各划分的图片数
+func (p *SplitPlan) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, sub := range p.Subsets {
+		counts[sub]++
+	}
+	return counts
+}
+
+// settingsKey 影响输出内容的处理参数；与上次不同时增量模式需要重新处理全部图片
+func (o Options) settingsKey() string {
+	return fmt.Sprintf("proc=%v kb=%d mode=%d kfold=%d resize=%v tile=%v aug=%v alpha=%v tiff=%d redact=%v ignore=%v classes=%s",
+		o.Process, o.MaxKB, o.OutputMode, o.Folds, o.Resize, o.Tile, o.Augment, o.Alpha, o.TiffPages, o.Redact, o.Ignore, strings.Join(o.Classes, ","))
+}
+
+// PlanSplits 给已打乱的 tasks 分配划分：增量模式先沿用 splits.json 中的记录，
+// 其余图片按比例 (可分组、分层) 补齐到目标张数。只读取标注与划分记录，不写入任何文件
+func (o Options) PlanSplits(tasks []FilePair) (*SplitPlan, error) {
+	names := SplitNames
+	var target []int
+	if o.Folds > 0 {
+		if o.Folds > len(tasks) {
+			return nil, fmt.Errorf("图片数 %d 少于折数 %d", len(tasks), o.Folds)
+		}
+		target = EqualCounts(len(tasks), o.Folds)
+		names = FoldNames(o.Folds)
+		o.logf(fmt.Sprintf(">>> K 折模式：%d 折，共用一份图片，Train/Val/Test 设置不生效", o.Folds))
+	} else {
+		counts, err := o.Split.Resolve(len(tasks))
+		if err != nil {
+			return nil, err
+		}
+		target = counts
+	}
+	o.logf(fmt.Sprintf("共 %d 张，目标划分: %s", len(tasks), DescribeCounts(names, target)))
+
+	var groupKeys []string
+	if o.Group != GroupNone {
+		groupKeys = GroupKeys(tasks, o.Group, o.GroupRe)
+		nGroups := make(map[string]bool)
+		for _, k := range groupKeys {
+			nGroups[k] = true
+		}
+		o.logf(fmt.Sprintf(">>> %s：%d 张图片分为 %d 组", GroupModeNames[o.Group], len(tasks), len(nGroups)))
+	}
+
+	p := &SplitPlan{Names: names, Subsets: make([]string, len(tasks))}
+	pending, err := o.keepAssignments(p, tasks, groupKeys)
+	if err != nil {
+		return nil, err
+	}
+	classes := o.assignPending(p, tasks, pending, target, groupKeys)
+
+	if o.Stratify {
+		o.logf(">>> 分层划分，各类别图片数:")
+		for _, line := range SplitClassTable(classes, p.Subsets, names, o.Classes) {
+			o.logf(line)
+		}
+	}
+	if groupKeys != nil || o.Folds > 0 || o.Incremental {
+		counts := p.Counts()
+		var parts []string
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s %d", name, counts[name]))
+		}
+		o.logf("划分结果: " + strings.Join(parts, " / "))
+	}
+	return p, nil
+}
+
+// keepAssignments 增量模式下沿用上次的划分 (新图片所在组已有划分时跟随该组)，返回仍需分配的图片下标
+func (o Options) keepAssignments(p *SplitPlan, tasks []FilePair, groupKeys []string) ([]int, error) {
+	settings := o.settingsKey()
+	p.Assign = &AssignmentFile{Seed: o.Seed, Settings: settings, Folds: o.Folds, Entries: make(map[string]Assignment)}
+	var pending []int
+	if !o.Incremental {
+		for i := range tasks {
+			pending = append(pending, i)
+		}
+		return pending, nil
+	}
+
+	prev, err := LoadAssignments(o.Output)
+	if err != nil {
+		return nil, err
+	}
+	p.Reuse = prev.Settings == settings
+	// 折数变化时旧的折与新的折对不上，全部重新划分；旧输出在处理时按记录删除
+	keepSplits := prev.Folds == o.Folds
+	switch {
+	case len(prev.Entries) == 0:
+	case !keepSplits:
+		o.logf(fmt.Sprintf("K 折数已变化 (%d → %d)，重新划分并处理全部图片", prev.Folds, o.Folds))
+	case !p.Reuse:
+		o.logf("处理参数已变化，保留划分但重新处理全部图片")
+	}
+	prev.Seed, prev.Settings, prev.Folds = o.Seed, settings, o.Folds
+	p.Assign = prev
+
+	validName := make(map[string]bool)
+	for _, n := range p.Names {
+		validName[n] = true
+	}
+	groupSplit := make(map[string]string)
+	for i, t := range tasks {
+		if a, ok := prev.Entries[SourceKey(t.ImgPath)]; ok && keepSplits && validName[a.Split] {
+			p.Subsets[i] = a.Split
+			if groupKeys != nil {
+				groupSplit[groupKeys[i]] = a.Split
+			}
+		}
+	}
+	kept := 0
+	for i := range tasks {
+		if p.Subsets[i] != "" {
+			kept++
+			continue
+		}
+		// 新图片所在组已有划分时跟随该组，避免同组泄漏
+		if groupKeys != nil {
+			if s, ok := groupSplit[groupKeys[i]]; ok {
+				p.Subsets[i] = s
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	o.logf(fmt.Sprintf(">>> 增量更新：沿用划分 %d 张，新分配 %d 张", kept, len(tasks)-kept))
+	return pending, nil
+}
+
+// assignPending 把未分配的图片补齐到目标张数；分层时返回每张图片包含的类别，供打印统计
+func (o Options) assignPending(p *SplitPlan, tasks []FilePair, pending, target []int, groupKeys []string) [][]int {
+	// 新图片的目标张数 = 总目标 - 沿用的张数
+	kept := make([]int, len(p.Names))
+	for _, sub := range p.Subsets {
+		for j, name := range p.Names {
+			if sub == name {
+				kept[j]++
+			}
+		}
+	}
+	pCounts := RemainingCounts(target, kept, len(pending))
+	ratios := CountsToRatios(pCounts)
+	var pGroupKeys []string
+	if groupKeys != nil {
+		for _, i := range pending {
+			pGroupKeys = append(pGroupKeys, groupKeys[i])
+		}
+	}
+
+	var idx []int
+	var classes [][]int
+	switch {
+	case o.Stratify:
+		// 预读每张图片包含的类别
+		clsMap, _, _, _ := o.labelIDs()
+		classes = make([][]int, len(tasks))
+		for i, t := range tasks {
+			boxes, _ := LoadLabelMeBoxes(t.JsonPath, clsMap)
+			for _, b := range boxes {
+				classes[i] = append(classes[i], b.Cls)
+			}
+		}
+		pClasses := make([][]int, len(pending))
+		for k, i := range pending {
+			pClasses[k] = classes[i]
+		}
+		// 整组分配时只能按比例逼近，不分组时各划分张数与目标一致
+		if pGroupKeys != nil {
+			idx = GroupStratifiedSplit(pGroupKeys, pClasses, ratios, o.Seed)
+		} else {
+			idx = StratifiedSplitCounts(pClasses, pCounts, o.Seed)
+		}
+	case pGroupKeys != nil:
+		idx = GroupSplit(pGroupKeys, ratios)
+	default:
+		idx = SplitByCounts(pCounts)
+	}
+	for k, i := range pending {
+		p.Subsets[i] = p.Names[idx[k]]
+	}
+	return classes
+}
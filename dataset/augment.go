@@ -0,0 +1,163 @@
+package dataset
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"math"
+	"math/rand"
+)
+
+// ==================== 离线增强 (仅训练集) ====================
+
+// AugmentOptions 离线增强参数
+type AugmentOptions struct {
+	Copies int     // 每张图额外生成的增强副本数，0 表示不增强
+	HFlip  bool    // 随机水平翻转
+	VFlip  bool    // 随机垂直翻转
+	Rot90  bool    // 随机旋转 0/90/180/270 度
+	Jitter float64 // 亮度/对比度/色相抖动幅度 0~1，0 表示不抖动
+	Crop   float64 // 随机裁剪的最小边长比例 0~1，0 表示不裁剪
+}
+
+// HasTransform 是否至少启用了一种变换；都未启用时副本与原图完全相同
+func (a AugmentOptions) HasTransform() bool {
+	return a.HFlip || a.VFlip || a.Rot90 || a.Jitter > 0 || (a.Crop > 0 && a.Crop < 1)
+}
+
+// 随机裁剪时框的最小可见比例
+const augCropMinVisible = 0.3
+
+// AugmentRand 按种子与源图片生成随机数，同一种子下结果可复现，与 worker 调度顺序无关
+func AugmentRand(seed int64, key string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+// Augment 生成 opt.Copies 个增强副本，文件名追加 _aug{i}；框与多边形随图片一起变换
+func Augment(v ImageVariant, opt AugmentOptions, rng *rand.Rand) []ImageVariant {
+	var out []ImageVariant
+	for i := 1; i <= opt.Copies; i++ {
+		img, boxes := toRGBA(v.Img), v.Boxes
+		if opt.Crop > 0 && opt.Crop < 1 {
+			img, boxes = randomCrop(img, boxes, opt.Crop, rng)
+		}
+		if opt.HFlip && rng.Intn(2) == 1 {
+			img, boxes = flipImage(img, boxes, true)
+		}
+		if opt.VFlip && rng.Intn(2) == 1 {
+			img, boxes = flipImage(img, boxes, false)
+		}
+		if opt.Rot90 {
+			for k := rng.Intn(4); k > 0; k-- {
+				img, boxes = rotate90(img, boxes)
+			}
+		}
+		if opt.Jitter > 0 {
+			colorJitter(img, opt.Jitter, rng)
+		}
+		out = append(out, ImageVariant{
+			Name:   fmt.Sprintf("%s_aug%d", v.Name, i),
+			Img:    img,
+			Boxes:  boxes,
+			Format: v.Format,
+		})
+	}
+	return out
+}
+
+// toRGBA 复制为以 (0,0) 为原点的 RGBA 图片，增强操作不改动原图
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// randomCrop 随机裁剪出边长为原图 minScale~1 倍的区域
+func randomCrop(img *image.RGBA, boxes []YoloBox, minScale float64, rng *rand.Rand) (*image.RGBA, []YoloBox) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	s := minScale + rng.Float64()*(1-minScale)
+	cw, ch := int(math.Max(1, math.Round(float64(w)*s))), int(math.Max(1, math.Round(float64(h)*s)))
+	x, y := rng.Intn(w-cw+1), rng.Intn(h-ch+1)
+	rect := image.Rect(x, y, x+cw, y+ch)
+	return toRGBA(img.SubImage(rect)), CropBoxes(boxes, rect, augCropMinVisible)
+}
+
+// flipImage 水平 (horizontal=true) 或垂直翻转
+func flipImage(img *image.RGBA, boxes []YoloBox, horizontal bool) (*image.RGBA, []YoloBox) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dst := image.NewRGBA(img.Bounds())
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := x, y
+			if horizontal {
+				sx = w - 1 - x
+			} else {
+				sy = h - 1 - y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst, mapBoxes(boxes, func(x, y float64) (float64, float64) {
+		if horizontal {
+			return float64(w) - x, y
+		}
+		return x, float64(h) - y
+	})
+}
+
+// rotate90 顺时针旋转 90 度，宽高互换
+func rotate90(img *image.RGBA, boxes []YoloBox) (*image.RGBA, []YoloBox) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < w; y++ {
+		for x := 0; x < h; x++ {
+			sx, sy := y, h-1-x
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst, mapBoxes(boxes, func(x, y float64) (float64, float64) {
+		return float64(h) - y, x
+	})
+}
+
+func mapBoxes(boxes []YoloBox, f func(x, y float64) (float64, float64)) []YoloBox {
+	out := make([]YoloBox, len(boxes))
+	for i, b := range boxes {
+		out[i] = b.MapPoints(f)
+	}
+	return out
+}
+
+// colorJitter 原地调整亮度、对比度与色相 (绕灰轴旋转 RGB)，幅度由 strength 控制
+func colorJitter(img *image.RGBA, strength float64, rng *rand.Rand) {
+	uniform := func() float64 { return (rng.Float64()*2 - 1) * strength }
+	brightness := uniform() * 64
+	contrast := 1 + uniform()
+	theta := uniform() * math.Pi / 6 // 最多 ±30°
+
+	// 色相旋转矩阵
+	c, s := math.Cos(theta), math.Sin(theta)
+	k := (1 - c) / 3
+	r := math.Sqrt(1.0/3) * s
+	m := [3][3]float64{
+		{c + k, k - r, k + r},
+		{k + r, c + k, k - r},
+		{k - r, k + r, c + k},
+	}
+
+	// RGBA 为预乘 alpha，分量不能超过 alpha
+	clamp := func(v float64, a uint8) uint8 {
+		return uint8(math.Max(0, math.Min(float64(a), math.Round(v))))
+	}
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		in := [3]float64{float64(img.Pix[i]), float64(img.Pix[i+1]), float64(img.Pix[i+2])}
+		for ch := 0; ch < 3; ch++ {
+			v := m[ch][0]*in[0] + m[ch][1]*in[1] + m[ch][2]*in[2]
+			img.Pix[i+ch] = clamp((v-128)*contrast+128+brightness, img.Pix[i+3])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package dataset
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestAugmentGeometry(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	box := YoloBox{Cls: 1, X1: 1, Y1: 2, X2: 4, Y2: 5, Poly: [][2]float64{{1, 2}, {4, 2}, {4, 5}, {1, 5}}}
+	tests := []struct {
+		name   string
+		apply  func(*image.RGBA, []YoloBox) (*image.RGBA, []YoloBox)
+		w, h   int
+		marker image.Point // 原图 (1,2) 处的像素移动到的位置
+		box    YoloBox     // 只比较外接框
+	}{
+		{"水平翻转", func(img *image.RGBA, bs []YoloBox) (*image.RGBA, []YoloBox) { return flipImage(img, bs, true) },
+			10, 6, image.Pt(8, 2), YoloBox{X1: 6, Y1: 2, X2: 9, Y2: 5}},
+		{"垂直翻转", func(img *image.RGBA, bs []YoloBox) (*image.RGBA, []YoloBox) { return flipImage(img, bs, false) },
+			10, 6, image.Pt(1, 3), YoloBox{X1: 1, Y1: 1, X2: 4, Y2: 4}},
+		{"顺时针旋转 90 度", rotate90, 6, 10, image.Pt(3, 1), YoloBox{X1: 1, Y1: 1, X2: 4, Y2: 4}},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 6))
+		img.SetRGBA(1, 2, red)
+		out, boxes := tt.apply(img, []YoloBox{box})
+		if b := out.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("%s: 尺寸 %dx%d, want %dx%d", tt.name, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if out.RGBAAt(tt.marker.X, tt.marker.Y) != red {
+			t.Errorf("%s: 像素未移动到 %v", tt.name, tt.marker)
+		}
+		g := boxes[0]
+		if g.Cls != box.Cls || g.X1 != tt.box.X1 || g.Y1 != tt.box.Y1 || g.X2 != tt.box.X2 || g.Y2 != tt.box.Y2 || len(g.Poly) != 4 {
+			t.Errorf("%s: 框 = %+v, want (%v,%v,%v,%v)", tt.name, g, tt.box.X1, tt.box.Y1, tt.box.X2, tt.box.Y2)
+		}
+		if img.RGBAAt(1, 2) != red {
+			t.Errorf("%s: 改动了原图", tt.name)
+		}
+	}
+}
+
+func TestRandomCrop(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	boxes := []YoloBox{
+		{Cls: 0, X1: 0, Y1: 0, X2: 100, Y2: 80},
+		{Cls: 1, X1: 90, Y1: 70, X2: 100, Y2: 80},
+		{Cls: 2, X1: 10, Y1: 10, X2: 60, Y2: 40, Poly: [][2]float64{{10, 10}, {60, 10}, {10, 40}}},
+	}
+	for seed := int64(1); seed <= 20; seed++ {
+		out, got := randomCrop(img, boxes, 0.5, rand.New(rand.NewSource(seed)))
+		w, h := out.Bounds().Dx(), out.Bounds().Dy()
+		if w < 50 || w > 100 || h < 40 || h > 80 || out.Bounds().Min != (image.Point{}) {
+			t.Errorf("seed %d: 裁剪区域 %v 超出 0.5~1 倍", seed, out.Bounds())
+		}
+		// 覆盖整图的框可见比例即裁剪面积占比，够 augCropMinVisible 时应变为整张新图
+		whole := float64(w*h)/(100*80) >= augCropMinVisible
+		if kept := len(got) > 0 && got[0].Cls == 0; kept != whole {
+			t.Errorf("seed %d: 整图框保留 = %v, want %v", seed, kept, whole)
+		} else if kept && (got[0].X1 != 0 || got[0].Y1 != 0 || got[0].X2 != float64(w) || got[0].Y2 != float64(h)) {
+			t.Errorf("seed %d: 整图框 = %+v, want (0,0,%d,%d)", seed, got[0], w, h)
+		}
+		// 裁剪后的框都在新图片内
+		for _, b := range got {
+			if b.X1 < 0 || b.Y1 < 0 || b.X2 > float64(w) || b.Y2 > float64(h) || b.X1 >= b.X2 || b.Y1 >= b.Y2 {
+				t.Errorf("seed %d: 框 %+v 超出 %dx%d", seed, b, w, h)
+			}
+		}
+	}
+}
+
+func TestAugment(t *testing.T) {
+	v := ImageVariant{Name: "a", Img: image.NewRGBA(image.Rect(0, 0, 20, 10)), Boxes: []YoloBox{{X1: 1, Y1: 1, X2: 5, Y2: 5}}, Format: "png"}
+	out := Augment(v, AugmentOptions{Copies: 3, HFlip: true, Rot90: true, Crop: 0.8}, AugmentRand(1, "a"))
+	if len(out) != 3 {
+		t.Fatalf("生成 %d 份, want 3", len(out))
+	}
+	for i, a := range out {
+		if want := fmt.Sprintf("a_aug%d", i+1); a.Name != want || a.Format != "png" || a.Src != nil {
+			t.Errorf("副本 %d: Name/Format/Src = %s/%s/%v, want %s/png/nil", i, a.Name, a.Format, a.Src != nil, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opt  AugmentOptions
+		want bool
+	}{
+		{"未启用任何变换", AugmentOptions{Copies: 2}, false},
+		{"裁剪比例为 1", AugmentOptions{Copies: 2, Crop: 1}, false},
+		{"水平翻转", AugmentOptions{Copies: 2, HFlip: true}, true},
+		{"颜色抖动", AugmentOptions{Copies: 2, Jitter: 0.1}, true},
+		{"裁剪", AugmentOptions{Copies: 2, Crop: 0.5}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.opt.HasTransform(); got != tt.want {
+			t.Errorf("%s: HasTransform() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
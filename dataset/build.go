@@ -0,0 +1,572 @@
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ==================== 逐张处理与写出 ====================
+
+const buildWorkers = 4 // 同时处理的图片数
+
+// builder 一次构建的写出状态，processImage 会被多个 worker 并发调用
+type builder struct {
+	o                    Options
+	plan                 *SplitPlan
+	loadMap              map[string]int
+	ignoreIDs, redactIDs map[int]bool
+	sink                 *trackedSink
+	absOut               string
+	manifest             *ManifestRecorder
+	failures             *errorList
+	total                int
+
+	listLock sync.Mutex
+	lists    map[string][]string // 各划分的图片绝对路径，列表模式与 K 折使用
+	owned    map[string]bool     // 上次写在源目录里的标签，列表模式可覆盖、过期时可删除，见 ListOwnedLabels
+
+	compress compressStats
+	fallback int32 // 无法建立链接、退回复制的文件
+	skipped  int32 // 未变化而沿用上次输出的图片
+	done     int32 // 已完成的源图片数 (worker 完成顺序不定)
+}
+
+// newBuilder 打开输出并创建目录结构
+func newBuilder(o Options, plan *SplitPlan, total int) (*builder, error) {
+	out, err := NewDatasetSink(o.Output)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建输出: %v", err)
+	}
+	switch {
+	case IsArchivePath(o.Output):
+		o.logf(">>> 写入压缩包: " + o.Output)
+	case o.OutputMode == OutputList:
+		if err := os.MkdirAll(o.Output, 0755); err != nil {
+			return nil, fmt.Errorf("无法创建目录: %v", err)
+		}
+	default:
+		dirs := SplitNames
+		if o.Folds > 0 {
+			dirs = []string{"all"}
+		}
+		for _, s := range dirs {
+			if err := os.MkdirAll(filepath.Join(o.Output, "images", s), 0755); err != nil {
+				return nil, fmt.Errorf("无法创建目录: %v", err)
+			}
+			os.MkdirAll(filepath.Join(o.Output, "labels", s), 0755)
+		}
+	}
+	b := &builder{
+		o:        o,
+		plan:     plan,
+		sink:     &trackedSink{DatasetSink: out, out: o.Output},
+		manifest: &ManifestRecorder{Seed: o.Seed},
+		failures: &errorList{},
+		lists:    make(map[string][]string),
+		total:    total,
+	}
+	_, b.loadMap, b.ignoreIDs, b.redactIDs = o.labelIDs()
+	if o.OutputMode == OutputList || o.Incremental {
+		b.owned = ListOwnedLabels(o.Output)
+	}
+	b.absOut, _ = filepath.Abs(o.Output)
+	return b, nil
+}
+
+func (b *builder) fail(path string, stage Stage, err error) {
+	b.o.logf("!!! " + b.failures.add(path, stage, err).String())
+}
+
+func (b *builder) progress(src, split string) {
+	done := int(atomic.AddInt32(&b.done, 1))
+	b.o.emit(Event{Kind: EventProgress, Done: done, Total: b.total, Source: src, Split: split})
+}
+
+func (b *builder) addToList(subset, p string) {
+	b.listLock.Lock()
+	b.lists[subset] = append(b.lists[subset], filepath.ToSlash(p))
+	b.listLock.Unlock()
+}
+
+// dir 输出子目录：K 折模式下所有图片只存一份，折的归属写在列表文件里
+func (b *builder) dir(subset string) string {
+	if b.o.Folds > 0 {
+		return "all"
+	}
+	return subset
+}
+
+// run 按划分逐张处理，最多 buildWorkers 张并行；暂停时不再派发，ctx 取消时等进行中的图片停下后返回
+func (b *builder) run(ctx context.Context, tasks []FilePair) {
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, buildWorkers)
+dispatch:
+	for i, t := range tasks {
+		if b.o.Pause.Wait(ctx) != nil {
+			break
+		}
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		wg.Add(1)
+		go func(task FilePair, subset string) {
+			defer wg.Done()
+			defer func() { <-limit }()
+			// 单张图片出现异常时记录下来，不影响其他图片
+			defer func() {
+				if r := recover(); r != nil {
+					b.fail(task.ImgPath, StagePanic, fmt.Errorf("%v", r))
+					b.progress(task.ImgPath, subset)
+				}
+			}()
+			if ctx.Err() != nil {
+				return
+			}
+			b.processImage(ctx, task, subset)
+			b.progress(task.ImgPath, subset)
+		}(t, b.plan.Subsets[i])
+	}
+	wg.Wait()
+}
+
+// processImage 处理一张源图片：未变化时沿用上次的输出，否则写出图片与标签并更新划分记录。
+// 失败记录在 failures 中；ctx 取消时在下一次写入前返回
+func (b *builder) processImage(ctx context.Context, task FilePair, subset string) {
+	key := SourceKey(task.ImgPath)
+	prev, hasPrev := b.plan.Assign.Get(key)
+	if b.plan.Reuse && hasPrev && prev.Split == subset && prev.Unchanged(b.o.Output, task) {
+		b.reuseOutputs(task, prev)
+		return
+	}
+
+	boxes, hasLabel, ok := b.loadBoxes(task)
+	if !ok {
+		return
+	}
+	rec := &Assignment{Split: subset, ImgStat: StatStamp(task.ImgPath), Json: StatStamp(task.JsonPath)}
+	if b.o.Process && b.o.OutputMode != OutputList {
+		b.writeVariants(ctx, task, rec, boxes, hasLabel)
+	} else {
+		b.placeOriginal(task, rec, boxes, hasLabel)
+	}
+	if len(rec.Images) == 0 {
+		return
+	}
+	if b.o.Incremental {
+		_, rec.ImgHash, _ = HashFile(task.ImgPath)
+	}
+	// 划分、文件名或切片数量变化时删除旧输出
+	if hasPrev {
+		RemoveStale(b.o.Output, prev.Files(), rec.Files(), b.owned)
+	}
+	b.plan.Assign.Set(key, *rec)
+}
+
+// reuseOutputs 源图片与标注都未变化，上次的输出原样保留，只补上清单与列表。
+// 清单条目取自划分记录中的大小与哈希；旧版记录没有这些信息时才重新哈希输出文件
+func (b *builder) reuseOutputs(task FilePair, prev Assignment) {
+	if entries, ok := prev.ManifestEntries(task.ImgPath, task.JsonPath); ok {
+		for _, e := range entries {
+			b.manifest.Add(e)
+		}
+	} else {
+		record := func(p, kind, source string) {
+			if e, err := b.manifest.RecordFile(p, kind, prev.Split, source, OutputPath(b.o.Output, p)); err != nil {
+				b.fail(p, StageWrite, err)
+			} else {
+				prev.Remember(e)
+			}
+		}
+		for _, p := range prev.Images {
+			record(p, "image", task.ImgPath)
+		}
+		for _, p := range prev.Labels {
+			record(p, "label", task.JsonPath)
+		}
+		b.plan.Assign.Set(SourceKey(task.ImgPath), prev)
+	}
+	if b.o.OutputMode == OutputList || b.o.Folds > 0 {
+		for _, p := range prev.Images {
+			b.addToList(prev.Split, OutputPath(b.absOut, p))
+		}
+	}
+	atomic.AddInt32(&b.skipped, 1)
+}
+
+// loadBoxes 读取标注 (原图像素坐标)；没有 JSON 时 hasLabel 为 false，不生成标签文件。
+// 启用忽略区域或脱敏时标注读取失败就无从得知要遮盖哪里，ok 为 false，整张图片不输出
+func (b *builder) loadBoxes(task FilePair) (boxes []YoloBox, hasLabel, ok bool) {
+	if _, err := os.Stat(task.JsonPath); err != nil {
+		return nil, false, true
+	}
+	boxes, err := LoadLabelMeBoxes(task.JsonPath, b.loadMap)
+	if err != nil {
+		if len(b.ignoreIDs) > 0 || len(b.redactIDs) > 0 {
+			b.fail(task.JsonPath, StageLabel, fmt.Errorf("%v (已启用忽略区域/脱敏，不输出该图片)", err))
+			return nil, false, false
+		}
+		b.fail(task.JsonPath, StageLabel, err)
+		return nil, false, true
+	}
+	return boxes, true, true
+}
+
+// writeLabel 写出一个标签文件，忽略/脱敏用的额外标签不写入
+func (b *builder) writeLabel(task FilePair, rec *Assignment, rel string, boxes []YoloBox, w, h int) {
+	var kept []YoloBox
+	for _, box := range boxes {
+		if box.Cls < len(b.o.Classes) {
+			kept = append(kept, box)
+		}
+	}
+	content := []byte(strings.Join(FormatYoloLines(kept, w, h), "\n"))
+	if b.o.OutputMode == OutputList {
+		b.sink.track(rel)
+		if err := os.WriteFile(rel, content, 0644); err != nil {
+			b.fail(rel, StageWrite, err)
+			return
+		}
+		rel = filepath.ToSlash(rel)
+	} else if err := b.sink.WriteFile(rel, content); err != nil {
+		b.fail(rel, StageWrite, err)
+		return
+	}
+	rec.Labels = append(rec.Labels, rel)
+	rec.Remember(b.manifest.Record(rel, "label", rec.Split, task.JsonPath, content))
+}
+
+// writeVariants 解码后展开为多个输出 (分页、切片、增强)，逐个缩放、压缩并写出
+func (b *builder) writeVariants(ctx context.Context, task FilePair, rec *Assignment, boxes []YoloBox, hasLabel bool) {
+	src, err := os.ReadFile(task.ImgPath)
+	if err != nil {
+		b.fail(task.ImgPath, StageRead, err)
+		return
+	}
+	img, format, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		b.fail(task.ImgPath, StageDecode, err)
+		return
+	}
+	dirSub := b.dir(rec.Split)
+	for _, v := range b.expandVariants(task, rec.Split, ImageVariant{Name: baseName(task.ImgPath), Img: img, Boxes: boxes, Src: src, Format: format}) {
+		if ctx.Err() != nil {
+			return
+		}
+		if b.o.Resize.Mode != ResizeNone {
+			out, xf := ApplyResize(v.Img, b.o.Resize)
+			if out != v.Img {
+				v.Img, v.Src, v.Boxes = out, nil, xf.Boxes(v.Boxes) // 已改动，不能再沿用源文件
+			}
+		}
+		res, err := SmartCompress(v.Img, v.Src, v.Format, b.o.MaxKB, b.o.Alpha)
+		if err != nil {
+			b.fail(task.ImgPath, StageCompress, fmt.Errorf("%s: %v", v.Name, err))
+			continue
+		}
+		rel := "images/" + dirSub + "/" + v.Name + res.Ext()
+		if err := b.sink.WriteFile(rel, res.Data); err != nil {
+			b.fail(rel, StageWrite, err)
+			continue
+		}
+		rec.Images = append(rec.Images, rel)
+		rec.Remember(b.manifest.RecordImage(rel, rec.Split, task.ImgPath, res.Data, res.Quality))
+		if b.o.Folds > 0 {
+			b.addToList(rec.Split, filepath.Join(b.absOut, filepath.FromSlash(rel)))
+		}
+		b.compress.add(res)
+		if b.o.Verbose || res.Oversize {
+			b.o.logf(DescribeCompress(rel, res))
+		}
+		if hasLabel {
+			bounds := v.Img.Bounds()
+			b.writeLabel(task, rec, "labels/"+dirSub+"/"+v.Name+".txt", v.Boxes, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
+
+// expandVariants 依次做 TIFF 分页、忽略区域、脱敏、切片与增强，返回要写出的全部图片
+func (b *builder) expandVariants(task FilePair, subset string, first ImageVariant) []ImageVariant {
+	variants := []ImageVariant{first}
+	// 多页 TIFF：其余页与第一页尺寸相同时共用同一份标注，文件名追加 _p{页码}
+	if first.Format == "tiff" && b.o.TiffPages == TiffAllPages {
+		pages, err := DecodeTiffPages(first.Src)
+		if err != nil {
+			b.fail(task.ImgPath, StageDecode, fmt.Errorf("读取 TIFF 分页失败: %v", err))
+		}
+		for i := 1; i < len(pages); i++ {
+			if pages[i].Bounds().Size() != first.Img.Bounds().Size() {
+				b.o.logf(fmt.Sprintf("%s: 第 %d 页尺寸与第一页不同，跳过", filepath.Base(task.ImgPath), i+1))
+				continue
+			}
+			variants = append(variants, ImageVariant{Name: fmt.Sprintf("%s_p%d", first.Name, i+1), Img: pages[i], Boxes: first.Boxes, Format: first.Format})
+		}
+	}
+	// 忽略区域填充中性色，落在其中的其他框一并删除
+	if len(b.ignoreIDs) > 0 {
+		for i, v := range variants {
+			regions, rest := SplitBoxes(v.Boxes, b.ignoreIDs)
+			if len(regions) == 0 {
+				continue
+			}
+			variants[i].Img, variants[i].Src = RedactRegions(v.Img, regions, RedactFill, b.o.Ignore.Fill), nil
+			variants[i].Boxes = DropCovered(rest, regions)
+		}
+	}
+	// 脱敏在切片、增强与缩放之前完成，后续输出都不含原始像素
+	if len(b.redactIDs) > 0 {
+		for i, v := range variants {
+			regions, rest := SplitBoxes(v.Boxes, b.redactIDs)
+			if len(regions) == 0 {
+				continue
+			}
+			variants[i].Img, variants[i].Src = RedactRegions(v.Img, regions, b.o.Redact.Mode, b.o.Redact.Fill), nil
+			if b.o.Redact.Drop {
+				variants[i].Boxes = rest
+			}
+		}
+	}
+	if b.o.Tile.Size > 0 {
+		var tiles []ImageVariant
+		for _, v := range variants {
+			tiles = append(tiles, TileImage(v, b.o.Tile)...)
+		}
+		variants = tiles
+	}
+	// 增强只作用于训练集，验证/测试集保持原样
+	if b.o.Augment.Copies > 0 && subset == "train" && b.o.Folds == 0 {
+		rng := AugmentRand(b.o.Seed, SourceKey(task.ImgPath))
+		for _, v := range variants {
+			variants = append(variants, Augment(v, b.o.Augment, rng)...)
+		}
+	}
+	return variants
+}
+
+// placeOriginal 不重新编码：按输出方式复制/链接原图，列表模式只记录路径；标签按原图尺寸换算
+func (b *builder) placeOriginal(task FilePair, rec *Assignment, boxes []YoloBox, hasLabel bool) {
+	f, err := os.Open(task.ImgPath)
+	if err != nil {
+		b.fail(task.ImgPath, StageRead, err)
+		return
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil {
+		b.fail(task.ImgPath, StageDecode, err)
+		return
+	}
+	dirSub := b.dir(rec.Split)
+	labelRel := "labels/" + dirSub + "/" + baseName(task.ImgPath) + ".txt"
+	if b.o.OutputMode == OutputList {
+		abs, _ := filepath.Abs(task.ImgPath)
+		labelRel = ListLabelPath(abs)
+		// 源图片旁已有别人的标签时不覆盖，图片也不列入列表，否则训练会读到那份标签
+		if _, err := os.Stat(labelRel); hasLabel && err == nil && !b.owned[filepath.ToSlash(labelRel)] {
+			b.fail(labelRel, StageWrite, fmt.Errorf("已存在不是本工具生成的标签文件，未覆盖"))
+			return
+		}
+		os.MkdirAll(filepath.Dir(labelRel), 0755)
+		b.addToList(rec.Split, abs)
+		rec.Images = append(rec.Images, filepath.ToSlash(abs))
+		if e, err := b.manifest.RecordFile(filepath.ToSlash(abs), "image", rec.Split, task.ImgPath, abs); err != nil {
+			b.fail(abs, StageWrite, err)
+		} else {
+			rec.Remember(e)
+		}
+	} else {
+		rel := "images/" + dirSub + "/" + baseName(task.ImgPath) + filepath.Ext(task.ImgPath)
+		used, err := b.sink.PlaceImage(task.ImgPath, rel, b.o.OutputMode)
+		if used != b.o.OutputMode && !IsArchivePath(b.o.Output) {
+			atomic.AddInt32(&b.fallback, 1)
+		}
+		if err != nil {
+			b.fail(rel, StageWrite, err)
+			return
+		}
+		rec.Images = append(rec.Images, rel)
+		if e, err := b.manifest.RecordFile(rel, "image", rec.Split, task.ImgPath, task.ImgPath); err != nil {
+			b.fail(rel, StageWrite, err)
+		} else {
+			rec.Remember(e)
+		}
+		if b.o.Folds > 0 {
+			b.addToList(rec.Split, filepath.Join(b.absOut, filepath.FromSlash(rel)))
+		}
+	}
+	if hasLabel {
+		b.writeLabel(task, rec, labelRel, boxes, cfg.Width, cfg.Height)
+	}
+}
+
+// finish 所有图片处理完后写出划分记录、列表、data.yaml 与清单，并关闭输出
+func (b *builder) finish(tasks []FilePair) {
+	o := b.o
+	if b.skipped > 0 {
+		o.logf(fmt.Sprintf("%d 张图片未变化，已跳过", b.skipped))
+	}
+	present := make(map[string]bool)
+	for _, t := range tasks {
+		present[SourceKey(t.ImgPath)] = true
+	}
+	if removed := b.plan.Assign.Prune(present); len(removed) > 0 {
+		for _, a := range removed {
+			RemoveStale(o.Output, a.Files(), nil, b.owned)
+		}
+		o.logf(fmt.Sprintf("%d 张源图片已不存在，已删除其输出", len(removed)))
+	}
+	if data, err := b.plan.Assign.Build(); err != nil {
+		b.fail(AssignmentName, StageWrite, err)
+	} else {
+		b.writeMeta(AssignmentName, data)
+	}
+	if line := b.compress.String(); line != "" {
+		o.logf(line)
+	}
+	if b.fallback > 0 && !o.Process {
+		o.logf(fmt.Sprintf("%d 个文件无法%s，已退回复制", b.fallback, OutputModeNames[o.OutputMode]))
+	}
+
+	if !IsArchivePath(o.Output) {
+		b.removeStaleFolds()
+	}
+	yamlOpts := o.yamlOptions()
+	if o.Folds > 0 {
+		// 第 i 折：fold_i 作验证集，其余各折合并为训练集
+		for i, name := range b.plan.Names {
+			var train []string
+			for j, other := range b.plan.Names {
+				if j != i {
+					train = append(train, b.lists[other]...)
+				}
+			}
+			b.writeList(name+"/train.txt", train)
+			b.writeList(name+"/val.txt", append([]string(nil), b.lists[name]...))
+			foldOpts := yamlOpts
+			foldOpts.Splits = []YamlSplit{{Key: "train", Path: name + "/train.txt"}, {Key: "val", Path: name + "/val.txt"}}
+			b.writeYaml(fmt.Sprintf("data_fold_%d.yaml", i), foldOpts)
+		}
+	} else {
+		counts := b.plan.Counts()
+		for _, key := range SplitNames {
+			if counts[key] == 0 {
+				continue
+			}
+			if o.OutputMode == OutputList {
+				b.writeList(key+".txt", b.lists[key])
+				yamlOpts.Splits = append(yamlOpts.Splits, YamlSplit{Key: key, Path: key + ".txt"})
+			} else {
+				yamlOpts.Splits = append(yamlOpts.Splits, YamlSplit{Key: key, Path: "images/" + key})
+			}
+		}
+		b.writeYaml("data.yaml", yamlOpts)
+	}
+	if data, err := b.manifest.Build(); err != nil {
+		b.fail(ManifestName, StageWrite, err)
+	} else if err := b.sink.WriteFile(ManifestName, data); err != nil {
+		b.fail(ManifestName, StageWrite, err)
+	}
+	if err := b.sink.Close(); err != nil {
+		b.fail(o.Output, StageWrite, err)
+	}
+}
+
+// removeStaleFolds 删除上次运行留下、本次折数之外的 fold_N/ 列表与 data_fold_N.yaml；
+// K 折模式下不再生成 data.yaml，一并删除
+func (b *builder) removeStaleFolds() {
+	if b.o.Folds > 0 {
+		os.Remove(filepath.Join(b.o.Output, "data.yaml"))
+	}
+	stale := func(name, prefix, suffix string) bool {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+		return err == nil && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix) && n >= b.o.Folds
+	}
+	entries, _ := os.ReadDir(b.o.Output)
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case e.IsDir() && stale(name, "fold_", ""):
+			dir := filepath.Join(b.o.Output, name)
+			os.Remove(filepath.Join(dir, "train.txt"))
+			os.Remove(filepath.Join(dir, "val.txt"))
+			os.Remove(dir) // 目录里还有别的文件时保留
+		case !e.IsDir() && stale(name, "data_fold_", ".yaml"):
+			os.Remove(filepath.Join(b.o.Output, name))
+		}
+	}
+}
+
+// writeMeta 写出一个元数据文件并登记到清单
+func (b *builder) writeMeta(rel string, data []byte) {
+	if err := b.sink.WriteFile(rel, data); err != nil {
+		b.fail(rel, StageWrite, err)
+		return
+	}
+	b.manifest.Record(rel, "meta", "", "", data)
+}
+
+func (b *builder) writeList(rel string, paths []string) {
+	sort.Strings(paths)
+	b.writeMeta(rel, []byte(strings.Join(paths, "\n")+"\n"))
+}
+
+func (b *builder) writeYaml(rel string, opts DataYamlOptions) {
+	data, err := BuildDataYaml(opts)
+	if err != nil {
+		b.fail(rel, StageWrite, err)
+		return
+	}
+	b.writeMeta(rel, data)
+}
+
+// compressStats 本次写出图片的压缩结果汇总，代替逐张日志
+type compressStats struct {
+	encoded, kept, png, oversize int32
+	bytes                        int64
+}
+
+func (c *compressStats) add(res CompressResult) {
+	switch {
+	case res.PNG:
+		atomic.AddInt32(&c.png, 1)
+	case res.Quality == 0:
+		atomic.AddInt32(&c.kept, 1)
+	default:
+		atomic.AddInt32(&c.encoded, 1)
+	}
+	if res.Oversize {
+		atomic.AddInt32(&c.oversize, 1)
+	}
+	atomic.AddInt64(&c.bytes, int64(len(res.Data)))
+}
+
+func (c *compressStats) String() string {
+	n := c.encoded + c.kept + c.png
+	if n == 0 {
+		return ""
+	}
+	line := fmt.Sprintf("压缩结果: 重新编码 %d 张，沿用原图 %d 张", c.encoded, c.kept)
+	if c.png > 0 {
+		line += fmt.Sprintf("，保留 PNG %d 张", c.png)
+	}
+	line += fmt.Sprintf("，共 %s，平均 %s", formatSize(c.bytes), formatSize(c.bytes/int64(n)))
+	if c.oversize > 0 {
+		line += fmt.Sprintf("；%d 张仍超过上限", c.oversize)
+	}
+	return line
+}
+
+// baseName 去掉目录与扩展名的文件名
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
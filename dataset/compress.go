@@ -0,0 +1,134 @@
+package dataset
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ==================== 图片压缩 ====================
+
+// CompressResult SmartCompress 的结果
+type CompressResult struct {
+	Data          []byte
+	Quality       int  // 0 表示沿用源 JPEG，未重新编码
+	Width, Height int  // 最终尺寸
+	Resized       bool // 因质量下限仍超限而缩小过尺寸
+	Oversize      bool // 缩到最小尺寸仍超过 maxKB
+	PNG           bool // 含透明通道，按设置保留为无损 PNG
+	Flattened     bool // 含透明通道，已铺底色后转 JPEG
+}
+
+// Ext 输出文件扩展名
+func (r CompressResult) Ext() string {
+	if r.PNG {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// SmartCompress 智能压缩：源文件本身是不超过 maxKB 的 JPEG 时直接沿用；
+// 否则二分查找满足大小的最高质量，质量降到下限仍超限时逐步缩小尺寸。
+// 含透明通道的图片按 alpha 设置铺底色或保留 PNG (PNG 不做有损压缩)。
+// src 为源文件字节，图片在内存中被修改过时传 nil；maxKB <= 0 表示不限大小
+func SmartCompress(img image.Image, src []byte, format string, maxKB int, alpha AlphaOptions) (CompressResult, error) {
+	const maxQuality, minQuality, minSide = 95, 20, 64
+	limit := maxKB * 1024
+	b := img.Bounds()
+	if src != nil && format == "jpeg" && (maxKB <= 0 || len(src) <= limit) {
+		return CompressResult{Data: src, Width: b.Dx(), Height: b.Dy()}, nil
+	}
+	flattened := false
+	if HasAlpha(img) {
+		if alpha.Mode == AlphaKeepPNG {
+			data, err := encodePNG(img, src, format)
+			return CompressResult{Data: data, Width: b.Dx(), Height: b.Dy(), PNG: true, Oversize: maxKB > 0 && len(data) > limit}, err
+		}
+		// jpeg.Encode 会把透明区域变成黑色，先铺底色
+		img, flattened = FlattenAlpha(img, alpha.Background), true
+	}
+	encode := func(im image.Image, q int) ([]byte, error) {
+		buf := new(bytes.Buffer)
+		err := jpeg.Encode(buf, im, &jpeg.Options{Quality: q})
+		return buf.Bytes(), err
+	}
+
+	cur := img
+	for {
+		cb := cur.Bounds()
+		res := CompressResult{Width: cb.Dx(), Height: cb.Dy(), Resized: cur != img, Flattened: flattened}
+		data, err := encode(cur, maxQuality)
+		if err != nil {
+			return res, err
+		}
+		if maxKB <= 0 || len(data) <= limit {
+			res.Data, res.Quality = data, maxQuality
+			return res, nil
+		}
+		// 二分查找能满足大小的最高质量
+		var smallest []byte
+		lo, hi := minQuality, maxQuality-1
+		for lo <= hi {
+			mid := (lo + hi) / 2
+			d, err := encode(cur, mid)
+			if err != nil {
+				return res, err
+			}
+			if len(d) <= limit {
+				res.Data, res.Quality = d, mid
+				lo = mid + 1
+			} else {
+				if mid == minQuality {
+					smallest = d
+				}
+				hi = mid - 1
+			}
+		}
+		if res.Data != nil {
+			return res, nil
+		}
+		// 最低质量仍超限：按面积估算缩放比例
+		if cb.Dx() <= minSide || cb.Dy() <= minSide {
+			res.Data, res.Quality, res.Oversize = smallest, minQuality, true
+			return res, nil
+		}
+		f := math.Sqrt(float64(limit)/float64(len(smallest))) * 0.95
+		f = math.Max(0.3, math.Min(0.9, f))
+		w := int(math.Max(minSide, float64(cb.Dx())*f))
+		h := int(math.Max(minSide, float64(cb.Dy())*f))
+		cur = ResizeImage(cur, w, h)
+	}
+}
+
+// DescribeCompress 单张图片的压缩结果说明，用于运行日志
+func DescribeCompress(name string, res CompressResult) string {
+	size := fmt.Sprintf("%dKB", (len(res.Data)+1023)/1024)
+	if res.Flattened {
+		size += ", 透明区域已铺底色"
+	}
+	switch {
+	case res.PNG && res.Oversize:
+		return fmt.Sprintf("%s: 含透明，保留 PNG, %s (超过上限)", name, size)
+	case res.PNG:
+		return fmt.Sprintf("%s: 含透明，保留 PNG, %s", name, size)
+	case res.Quality == 0:
+		return fmt.Sprintf("%s: 沿用原图, %s", name, size)
+	case res.Oversize:
+		return fmt.Sprintf("%s: 质量 %d, 缩放至 %dx%d, %s (仍超过上限)", name, res.Quality, res.Width, res.Height, size)
+	case res.Resized:
+		return fmt.Sprintf("%s: 质量 %d, 缩放至 %dx%d, %s", name, res.Quality, res.Width, res.Height, size)
+	default:
+		return fmt.Sprintf("%s: 质量 %d, %s", name, res.Quality, size)
+	}
+}
+
+// ResizeImage 高质量缩放 (Catmull-Rom)
+func ResizeImage(img image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
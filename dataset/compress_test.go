@@ -0,0 +1,105 @@
+package dataset
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+)
+
+// noiseImage 随机噪声图，JPEG 压缩率低，便于构造超限的情况；alpha 为全图的不透明度
+func noiseImage(w, h int, alpha uint8) *image.NRGBA {
+	r := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.Intn(256))
+		if i%4 == 3 {
+			img.Pix[i] = alpha
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image, q int) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: q}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSmartCompress(t *testing.T) {
+	img := noiseImage(128, 128, 255)
+	src := encodeJPEG(t, img, 80)
+	white := AlphaOptions{Mode: AlphaComposite, Background: color.RGBA{255, 255, 255, 255}}
+	tests := []struct {
+		name    string
+		img     image.Image
+		src     []byte
+		format  string
+		maxKB   int
+		alpha   AlphaOptions
+		quality int // -1 表示只检查在 20~94 之间
+		resized bool
+		png     bool
+	}{
+		{"源 JPEG 未超限沿用原图", img, src, "jpeg", 100, white, 0, false, false},
+		{"源 JPEG 不限大小", img, src, "jpeg", 0, white, 0, false, false},
+		{"不限大小用最高质量", img, nil, "png", 0, white, 95, false, false},
+		{"超限时降低质量", img, src, "jpeg", 10, white, -1, false, false},
+		{"质量下限仍超限时缩小", img, nil, "png", 2, white, -1, true, false},
+		{"透明图保留 PNG", noiseImage(32, 32, 128), nil, "png", 100, AlphaOptions{Mode: AlphaKeepPNG}, 0, false, true},
+	}
+	for _, tt := range tests {
+		res, err := SmartCompress(tt.img, tt.src, tt.format, tt.maxKB, tt.alpha)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.quality >= 0 && res.Quality != tt.quality {
+			t.Errorf("%s: 质量 = %d, want %d", tt.name, res.Quality, tt.quality)
+		}
+		if tt.quality < 0 && (res.Quality < 20 || res.Quality > 94) {
+			t.Errorf("%s: 质量 = %d, want 20~94", tt.name, res.Quality)
+		}
+		if res.Resized != tt.resized || res.PNG != tt.png || res.Oversize {
+			t.Errorf("%s: Resized/PNG/Oversize = %v/%v/%v, want %v/%v/false", tt.name, res.Resized, res.PNG, res.Oversize, tt.resized, tt.png)
+		}
+		if tt.maxKB > 0 && len(res.Data) > tt.maxKB*1024 {
+			t.Errorf("%s: %d 字节，超过上限 %dKB", tt.name, len(res.Data), tt.maxKB)
+		}
+		if tt.quality == 0 && tt.src != nil && !bytes.Equal(res.Data, tt.src) {
+			t.Errorf("%s: 未沿用源文件", tt.name)
+		}
+		if tt.resized && (res.Width >= 128 || res.Width != res.Height) {
+			t.Errorf("%s: 尺寸 %dx%d，应等比缩小", tt.name, res.Width, res.Height)
+		}
+		// 二分查找应找到满足大小的最高质量
+		if !tt.resized && res.Quality > 0 && res.Quality < 95 {
+			if next := encodeJPEG(t, img, res.Quality+1); len(next) <= tt.maxKB*1024 {
+				t.Errorf("%s: 质量 %d 也不超限，应选更高的质量", tt.name, res.Quality+1)
+			}
+		}
+	}
+}
+
+func TestSmartCompressFlatten(t *testing.T) {
+	// 全透明图铺白底后应为白色，而不是 jpeg.Encode 默认的黑色
+	res, err := SmartCompress(noiseImage(16, 16, 0), nil, "png", 100, AlphaOptions{Mode: AlphaComposite, Background: color.RGBA{255, 255, 255, 255}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Flattened || res.PNG || res.Ext() != ".jpg" {
+		t.Fatalf("Flattened/PNG/Ext = %v/%v/%s", res.Flattened, res.PNG, res.Ext())
+	}
+	out, err := jpeg.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := out.At(8, 8).RGBA(); r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("透明区域颜色 = %d,%d,%d, want 白色", r>>8, g>>8, b>>8)
+	}
+}
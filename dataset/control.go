@@ -0,0 +1,71 @@
+package dataset
+
+import (
+	"context"
+	"sync"
+)
+
+// ==================== 暂停 / 继续 ====================
+
+// Pauser 构建的暂停开关：暂停后不再开始新的图片，进行中的图片照常完成
+type Pauser struct {
+	mu   sync.Mutex
+	gate chan struct{} // 运行时为已关闭的通道，暂停时为未关闭的通道
+}
+
+// NewPauser 创建处于运行状态的开关
+func NewPauser() *Pauser {
+	gate := make(chan struct{})
+	close(gate)
+	return &Pauser{gate: gate}
+}
+
+// Pause 暂停，重复调用无影响
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.gate:
+		p.gate = make(chan struct{})
+	default:
+	}
+}
+
+// Resume 继续，重复调用无影响
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.gate:
+	default:
+		close(p.gate)
+	}
+}
+
+// Paused 当前是否处于暂停状态
+func (p *Pauser) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.gate:
+		return false
+	default:
+		return true
+	}
+}
+
+// Wait 暂停期间阻塞，直到继续或 ctx 取消；p 为空时立即返回
+func (p *Pauser) Wait(ctx context.Context) error {
+	if p == nil {
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	gate := p.gate
+	p.mu.Unlock()
+	select {
+	case <-gate:
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
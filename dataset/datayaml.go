@@ -0,0 +1,121 @@
+package dataset
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ==================== data.yaml 生成 ====================
+
+// YamlSplit data.yaml 中的一个划分条目
+type YamlSplit struct {
+	Key  string // train / val / test
+	Path string // 相对数据集根目录的路径，如 images/train
+}
+
+// DataYamlOptions data.yaml 生成参数
+type DataYamlOptions struct {
+	Root     string      // 数据集根目录，写入 path 字段
+	Relative bool        // 省略 path 字段，由训练框架按 data.yaml 所在目录解析
+	Splits   []YamlSplit // 只应包含非空的划分
+	Names    []string    // 类别名，下标即类别 ID
+	Extra    string      // 附加键，YAML 映射文本
+}
+
+// 由工具生成的键，附加键不允许覆盖
+var reservedYamlKeys = map[string]bool{
+	"path": true, "train": true, "val": true, "test": true, "nc": true, "names": true,
+}
+
+// ParseClassList 解析逗号分隔的类别列表，拒绝空名和重复名
+func ParseClassList(text string) ([]string, error) {
+	text = strings.ReplaceAll(text, "，", ",")
+	var names []string
+	seen := make(map[string]int)
+	for i, c := range strings.Split(text, ",") {
+		name := strings.TrimSpace(c)
+		if name == "" {
+			return nil, fmt.Errorf("第 %d 个类别名为空", i+1)
+		}
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("类别名重复: %q (第 %d 和第 %d 个)", name, prev+1, i+1)
+		}
+		seen[name] = i
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// YAML 1.1 (PyYAML) 会当作布尔/空值解析的词，yaml.v3 按 1.2 规则不会加引号
+var yaml11Words = map[string]bool{
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true, "~": true,
+}
+
+func yamlStr(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	// 数字开头的串在 1.1 下可能被解析为 1_000、1:30 这类数字，一并加引号
+	if yaml11Words[strings.ToLower(s)] || (s != "" && strings.ContainsRune("0123456789+-.", rune(s[0]))) {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+// BuildDataYaml 生成 data.yaml 内容 (字符串统一经 YAML 编码器转义)
+func BuildDataYaml(opts DataYamlOptions) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, val *yaml.Node) {
+		root.Content = append(root.Content, yamlStr(key), val)
+	}
+
+	if !opts.Relative {
+		add("path", yamlStr(opts.Root))
+	}
+	for _, s := range opts.Splits {
+		add(s.Key, yamlStr(s.Path))
+	}
+	add("nc", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(len(opts.Names))})
+	names := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, n := range opts.Names {
+		names.Content = append(names.Content, yamlStr(n))
+	}
+	add("names", names)
+
+	if strings.TrimSpace(opts.Extra) != "" {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(opts.Extra), &doc); err != nil {
+			return nil, fmt.Errorf("附加键解析失败: %v", err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("附加键必须是 key: value 形式的映射")
+		}
+		extra := doc.Content[0]
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(extra.Content); i += 2 {
+			key := extra.Content[i].Value
+			if reservedYamlKeys[key] {
+				return nil, fmt.Errorf("附加键 %q 由工具生成，不能覆盖", key)
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("附加键 %q 重复", key)
+			}
+			seen[key] = true
+			root.Content = append(root.Content, extra.Content[i], extra.Content[i+1])
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
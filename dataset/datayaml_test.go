@@ -0,0 +1,53 @@
+package dataset
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildDataYaml(t *testing.T) {
+	splits := []YamlSplit{{Key: "train", Path: "images/train"}, {Key: "val", Path: "images/val"}}
+	tests := []struct {
+		name string
+		opts DataYamlOptions
+		want string // 为空时 err 不能为空
+		err  string
+	}{
+		{
+			name: "绝对路径",
+			opts: DataYamlOptions{Root: "/data/out", Splits: splits, Names: []string{"cat", "dog"}},
+			want: "path: /data/out\ntrain: images/train\nval: images/val\nnc: 2\nnames:\n  - cat\n  - dog\n",
+		},
+		{
+			name: "相对路径省略 path",
+			opts: DataYamlOptions{Root: "/data/out", Relative: true, Splits: splits[:1], Names: []string{"cat"}},
+			want: "train: images/train\nnc: 1\nnames:\n  - cat\n",
+		},
+		{
+			name: "YAML 1.1 会误解析的类别名加引号",
+			opts: DataYamlOptions{Relative: true, Names: []string{"no", "1_000", "-x", "ok"}},
+			want: "nc: 4\nnames:\n  - \"no\"\n  - \"1_000\"\n  - \"-x\"\n  - ok\n",
+		},
+		{
+			name: "附加键追加在末尾",
+			opts: DataYamlOptions{Relative: true, Names: []string{"cat"}, Extra: "download: https://example.com/d.zip\nkpt_shape: [17, 3]"},
+			want: "nc: 1\nnames:\n  - cat\ndownload: https://example.com/d.zip\nkpt_shape: [17, 3]\n",
+		},
+		{name: "附加键不能覆盖生成的键", opts: DataYamlOptions{Names: []string{"cat"}, Extra: "nc: 3"}, err: "不能覆盖"},
+		{name: "附加键重复", opts: DataYamlOptions{Names: []string{"cat"}, Extra: "a: 1\na: 2"}, err: "重复"},
+		{name: "附加键不是映射", opts: DataYamlOptions{Names: []string{"cat"}, Extra: "- a\n- b"}, err: "映射"},
+		{name: "附加键语法错误", opts: DataYamlOptions{Names: []string{"cat"}, Extra: "a: [1"}, err: "解析失败"},
+	}
+	for _, tt := range tests {
+		got, err := BuildDataYaml(tt.opts)
+		if tt.want == "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: got\n%s(err %v)\nwant\n%s", tt.name, got, err, tt.want)
+		}
+	}
+}
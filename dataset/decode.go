@@ -0,0 +1,95 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// ==================== 输入格式 (BMP / TIFF / WebP) ====================
+
+// 可作为数据源的图片扩展名 (小写)
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".bmp": true,
+	".tif": true, ".tiff": true, ".webp": true,
+}
+
+// IsImageExt 扩展名是否为支持的图片格式
+func IsImageExt(ext string) bool {
+	return imageExts[strings.ToLower(ext)]
+}
+
+// TiffPageMode 多页 TIFF 的处理方式
+type TiffPageMode int
+
+const (
+	TiffFirstPage TiffPageMode = iota // 只取第一页
+	TiffAllPages                      // 每页输出一张图，共用同一份标注
+)
+
+var TiffPageModeNames = []string{"TIFF 只取第一页", "TIFF 输出全部页"}
+
+// 多页处理方式的英文键名
+var TiffPageModeKeys = []string{"first", "all"}
+
+// tiffPageOffsets 依次读取 TIFF 的 IFD 链，返回每一页 IFD 的偏移
+func tiffPageOffsets(data []byte) ([]uint32, binary.ByteOrder, error) {
+	if len(data) < 8 {
+		return nil, nil, fmt.Errorf("TIFF 文件头不完整")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, fmt.Errorf("不是 TIFF 文件")
+	}
+	var offsets []uint32
+	seen := make(map[uint32]bool)
+	for off := order.Uint32(data[4:8]); off != 0; {
+		// 偏移越界或成环时停止，已读到的页仍可用
+		if seen[off] || int(off)+2 > len(data) {
+			break
+		}
+		seen[off] = true
+		offsets = append(offsets, off)
+		n := int(order.Uint16(data[off:]))
+		next := int(off) + 2 + n*12
+		if next+4 > len(data) {
+			break
+		}
+		off = order.Uint32(data[next:])
+	}
+	if len(offsets) == 0 {
+		return nil, nil, fmt.Errorf("TIFF 中没有图像")
+	}
+	return offsets, order, nil
+}
+
+// DecodeTiffPages 解码多页 TIFF 的全部页：逐页把文件头的首个 IFD 偏移改为该页再解码
+func DecodeTiffPages(data []byte) ([]image.Image, error) {
+	offsets, order, err := tiffPageOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte(nil), data...)
+	var pages []image.Image
+	for i, off := range offsets {
+		order.PutUint32(buf[4:8], off)
+		img, err := tiff.Decode(bytes.NewReader(buf))
+		if err != nil {
+			return pages, fmt.Errorf("第 %d 页: %v", i+1, err)
+		}
+		pages = append(pages, img)
+	}
+	return pages, nil
+}
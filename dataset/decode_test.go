@@ -0,0 +1,78 @@
+package dataset
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// tiffChain 构造只有 IFD 链的 TIFF 字节：IFD 依次紧接文件头，每个 IFD 含 entries 个空条目；
+// next 为 nil 时各页依次相连，否则 next[i] 为第 i 页记录的下一页偏移
+func tiffChain(order binary.ByteOrder, entries []int, next []uint32) ([]byte, []uint32) {
+	data := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(data, "II")
+	} else {
+		copy(data, "MM")
+	}
+	order.PutUint16(data[2:], 42)
+	var offsets []uint32
+	off := uint32(8)
+	for _, n := range entries {
+		offsets = append(offsets, off)
+		off += uint32(2 + n*12 + 4)
+	}
+	if len(offsets) > 0 {
+		order.PutUint32(data[4:], offsets[0])
+	}
+	for i, n := range entries {
+		ifd := make([]byte, 2+n*12+4)
+		order.PutUint16(ifd, uint16(n))
+		switch {
+		case next != nil:
+			order.PutUint32(ifd[2+n*12:], next[i])
+		case i+1 < len(offsets):
+			order.PutUint32(ifd[2+n*12:], offsets[i+1])
+		}
+		data = append(data, ifd...)
+	}
+	return data, offsets
+}
+
+func TestTiffPageOffsets(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	single, singleOffs := tiffChain(le, []int{3}, nil)
+	three, threeOffs := tiffChain(be, []int{1, 2, 0}, nil)
+	loop, loopOffs := tiffChain(le, []int{1, 1}, []uint32{26, 8})
+	outside, outsideOffs := tiffChain(le, []int{1}, []uint32{4096})
+	truncated := append([]byte(nil), three[:len(three)-10]...)
+	empty, _ := tiffChain(le, nil, nil)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		want  []uint32 // 为 nil 表示应当报错
+		order binary.ByteOrder
+	}{
+		{"单页", single, singleOffs, le},
+		{"大端多页", three, threeOffs, be},
+		{"成环时停止", loop, loopOffs, le},
+		{"下一页越界时停止", outside, outsideOffs, le},
+		{"最后一页被截断", truncated, threeOffs[:2], be},
+		{"文件头不完整", []byte("II*\x00"), nil, nil},
+		{"不是 TIFF", []byte("PK\x03\x04\x08\x00\x00\x00"), nil, nil},
+		{"没有图像", empty, nil, nil},
+	}
+	for _, tt := range tests {
+		got, order, err := tiffPageOffsets(tt.data)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: got %v, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) || order != tt.order {
+			t.Errorf("%s: got %v, %v, %v, want %v, %v", tt.name, got, order, err, tt.want, tt.order)
+		}
+	}
+}
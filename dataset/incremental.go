@@ -0,0 +1,201 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ==================== 增量更新 (划分记录) ====================
+
+// AssignmentName 划分记录文件名，位于数据集根目录
+const AssignmentName = "splits.json"
+
+// SourceStamp 源文件的大小与修改时间，用于判断是否需要重新处理
+type SourceStamp struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"` // UnixNano
+}
+
+// StatStamp 读取文件戳，文件不存在时返回零值
+func StatStamp(path string) SourceStamp {
+	st, err := os.Stat(path)
+	if err != nil {
+		return SourceStamp{}
+	}
+	return SourceStamp{Size: st.Size(), ModTime: st.ModTime().UnixNano()}
+}
+
+// Assignment 一张源图片的划分与输出记录
+type Assignment struct {
+	Split   string      `json:"split"`
+	Images  []string    `json:"images,omitempty"` // 输出图片 (相对根目录；列表模式为绝对路径)，切片时有多张
+	Labels  []string    `json:"labels,omitempty"` // 输出标签，同上
+	ImgStat SourceStamp `json:"img_stat"`
+	ImgHash string      `json:"img_sha256,omitempty"`
+	Json    SourceStamp `json:"json_stat"`
+	// Outputs 各输出文件写出时的大小与哈希，沿用输出时直接写入清单，不必重新读取
+	Outputs map[string]OutputSum `json:"outputs,omitempty"`
+}
+
+// OutputSum 一个输出文件的大小、哈希与压缩质量
+type OutputSum struct {
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Quality int    `json:"quality,omitempty"`
+}
+
+// Remember 记下刚登记到清单的输出
+func (a *Assignment) Remember(e ManifestEntry) {
+	if a.Outputs == nil {
+		a.Outputs = make(map[string]OutputSum)
+	}
+	a.Outputs[e.Path] = OutputSum{Size: e.Size, SHA256: e.SHA256, Quality: e.Quality}
+}
+
+// ManifestEntries 按记录中的大小与哈希还原清单条目；旧版记录缺少某个输出时 ok 为 false
+func (a Assignment) ManifestEntries(imgSource, labelSource string) (entries []ManifestEntry, ok bool) {
+	add := func(paths []string, kind, source string) bool {
+		for _, p := range paths {
+			sum, found := a.Outputs[p]
+			if !found {
+				return false
+			}
+			entries = append(entries, ManifestEntry{Path: p, Kind: kind, Split: a.Split, Size: sum.Size, SHA256: sum.SHA256, Source: source, Quality: sum.Quality})
+		}
+		return true
+	}
+	ok = add(a.Images, "image", imgSource) && add(a.Labels, "label", labelSource)
+	return entries, ok
+}
+
+// AssignmentFile 划分记录文件内容，键为源图片的绝对路径 (/ 分隔)
+type AssignmentFile struct {
+	Seed     int64                 `json:"seed"`
+	Settings string                `json:"settings"`        // 处理参数指纹，变化时全部重新处理
+	Folds    int                   `json:"folds,omitempty"` // K 折数，变化时重新划分
+	Entries  map[string]Assignment `json:"entries"`
+
+	mu sync.Mutex
+}
+
+// LoadAssignments 读取输出目录中的划分记录，不存在时返回空记录
+func LoadAssignments(root string) (*AssignmentFile, error) {
+	af := &AssignmentFile{Entries: make(map[string]Assignment)}
+	raw, err := os.ReadFile(filepath.Join(root, AssignmentName))
+	if os.IsNotExist(err) {
+		return af, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, af); err != nil {
+		return nil, fmt.Errorf("%s 格式错误: %v", AssignmentName, err)
+	}
+	if af.Entries == nil {
+		af.Entries = make(map[string]Assignment)
+	}
+	return af, nil
+}
+
+// SourceKey 划分记录中源图片的键
+func SourceKey(imgPath string) string {
+	abs, err := filepath.Abs(imgPath)
+	if err != nil {
+		abs = imgPath
+	}
+	return filepath.ToSlash(abs)
+}
+
+// Unchanged 源图片与 JSON 自上次处理后未变化，且之前的输出仍在
+// (大小相同但修改时间变化时，再用哈希确认)
+func (a Assignment) Unchanged(root string, task FilePair) bool {
+	if len(a.Images) == 0 || StatStamp(task.JsonPath) != a.Json {
+		return false
+	}
+	img := StatStamp(task.ImgPath)
+	if img.Size != a.ImgStat.Size {
+		return false
+	}
+	if img.ModTime != a.ImgStat.ModTime {
+		if _, sum, err := HashFile(task.ImgPath); err != nil || sum != a.ImgHash {
+			return false
+		}
+	}
+	for _, p := range a.Files() {
+		if _, err := os.Stat(OutputPath(root, p)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Files 全部输出文件
+func (a Assignment) Files() []string {
+	return append(append([]string(nil), a.Images...), a.Labels...)
+}
+
+// RemoveStale 删除 old 中不在 keep 里的输出文件。绝对路径是列表模式写在源目录里的标签，
+// 只删除 owned (见 ListOwnedLabels) 中记录的，其余可能是用户自己的文件
+func RemoveStale(root string, old, keep []string, owned map[string]bool) {
+	kept := make(map[string]bool)
+	for _, p := range keep {
+		kept[p] = true
+	}
+	for _, p := range old {
+		if kept[p] || (filepath.IsAbs(filepath.FromSlash(p)) && !owned[p]) {
+			continue
+		}
+		os.Remove(OutputPath(root, p))
+	}
+}
+
+// OutputPath 记录中的输出路径转为磁盘路径
+func OutputPath(root, p string) string {
+	if filepath.IsAbs(filepath.FromSlash(p)) {
+		return filepath.FromSlash(p)
+	}
+	return filepath.Join(root, filepath.FromSlash(p))
+}
+
+// Get 并发安全地读取一条记录
+func (af *AssignmentFile) Get(key string) (Assignment, bool) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	a, ok := af.Entries[key]
+	return a, ok
+}
+
+// Set 并发安全地更新一条记录
+func (af *AssignmentFile) Set(key string, a Assignment) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	af.Entries[key] = a
+}
+
+// Prune 删除本次扫描中已不存在的源图片，返回被删除的记录
+func (af *AssignmentFile) Prune(present map[string]bool) []Assignment {
+	var removed []Assignment
+	keys := make([]string, 0, len(af.Entries))
+	for k := range af.Entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !present[k] {
+			removed = append(removed, af.Entries[k])
+			delete(af.Entries, k)
+		}
+	}
+	return removed
+}
+
+// Build 序列化划分记录
+func (af *AssignmentFile) Build() ([]byte, error) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	return json.MarshalIndent(af, "", "  ")
+}
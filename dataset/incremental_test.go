@@ -0,0 +1,30 @@
+package dataset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignmentManifestEntries(t *testing.T) {
+	img := ManifestEntry{Path: "images/train/a.jpg", Kind: "image", Split: "train", Size: 10, SHA256: "aa", Source: "/src/a.png", Quality: 90}
+	label := ManifestEntry{Path: "labels/train/a.txt", Kind: "label", Split: "train", Size: 3, SHA256: "bb", Source: "/src/a.json"}
+
+	a := Assignment{Split: "train", Images: []string{img.Path}, Labels: []string{label.Path}}
+	a.Remember(img)
+	a.Remember(label)
+	tests := []struct {
+		name string
+		a    Assignment
+		want []ManifestEntry // 为 nil 表示记录不完整，需要重新哈希
+	}{
+		{"完整记录", a, []ManifestEntry{img, label}},
+		{"旧版记录", Assignment{Split: "train", Images: []string{img.Path}}, nil},
+		{"缺少标签", Assignment{Split: "train", Images: a.Images, Labels: []string{"labels/train/b.txt"}, Outputs: a.Outputs}, nil},
+	}
+	for _, tt := range tests {
+		got, ok := tt.a.ManifestEntries("/src/a.png", "/src/a.json")
+		if ok != (tt.want != nil) || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.name, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ==================== LabelMe 标注读取 ====================
+
+// YoloBox 像素坐标下的一个标注框
+type YoloBox struct {
+	Cls            int
+	X1, Y1, X2, Y2 float64
+	Poly           [][2]float64 // 多边形顶点，矩形标注为 nil；几何变换时与框一起变换
+}
+
+// MapPoints 对框做点变换：有多边形时变换顶点后重新求外接框，否则变换四个角
+func (b YoloBox) MapPoints(f func(x, y float64) (float64, float64)) YoloBox {
+	pts := b.Poly
+	if pts == nil {
+		pts = [][2]float64{{b.X1, b.Y1}, {b.X2, b.Y1}, {b.X2, b.Y2}, {b.X1, b.Y2}}
+	}
+	mapped := make([][2]float64, len(pts))
+	for i, p := range pts {
+		x, y := f(p[0], p[1])
+		mapped[i] = [2]float64{x, y}
+	}
+	out := BoundingBox(b.Cls, mapped)
+	if b.Poly == nil {
+		out.Poly = nil
+	}
+	return out
+}
+
+// BoundingBox 由多边形顶点求外接框
+func BoundingBox(cls int, pts [][2]float64) YoloBox {
+	minX, minY := math.MaxFloat64, math.MaxFloat64
+	maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	return YoloBox{Cls: cls, X1: minX, Y1: minY, X2: maxX, Y2: maxY, Poly: pts}
+}
+
+type labelMeShape struct {
+	Label  string      `json:"label"`
+	Points [][]float64 `json:"points"`
+}
+
+// labelMeJSON LabelMe 文件中用到的字段；labels 为旧版矩形格式
+type labelMeJSON struct {
+	Shapes []labelMeShape `json:"shapes"`
+	Labels []struct {
+		Name string  `json:"name"`
+		X1   float64 `json:"x1"`
+		Y1   float64 `json:"y1"`
+		X2   float64 `json:"x2"`
+		Y2   float64 `json:"y2"`
+	} `json:"labels"`
+}
+
+func readLabelMe(jsonPath string) (labelMeJSON, error) {
+	var data labelMeJSON
+	fileBytes, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return data, err
+	}
+	err = json.Unmarshal(fileBytes, &data)
+	return data, err
+}
+
+// LabelMeNames 读取 LabelMe JSON 中全部标注的标签名 (每个标注一项，可重复)
+func LabelMeNames(jsonPath string) ([]string, error) {
+	data, err := readLabelMe(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, shape := range data.Shapes {
+		names = append(names, shape.Label)
+	}
+	for _, lbl := range data.Labels {
+		names = append(names, lbl.Name)
+	}
+	return names, nil
+}
+
+// LoadLabelMeBoxes 读取 LabelMe JSON 中属于 classMap 的标注框 (像素坐标)
+func LoadLabelMeBoxes(jsonPath string, classMap map[string]int) ([]YoloBox, error) {
+	data, err := readLabelMe(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	var boxes []YoloBox
+
+	for _, shape := range data.Shapes {
+		if id, ok := classMap[shape.Label]; ok && len(shape.Points) > 0 {
+			minX, minY := math.MaxFloat64, math.MaxFloat64
+			maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
+			for _, p := range shape.Points {
+				if len(p) >= 2 {
+					if p[0] < minX {
+						minX = p[0]
+					}
+					if p[0] > maxX {
+						maxX = p[0]
+					}
+					if p[1] < minY {
+						minY = p[1]
+					}
+					if p[1] > maxY {
+						maxY = p[1]
+					}
+				}
+			}
+			box := YoloBox{Cls: id, X1: minX, Y1: minY, X2: maxX, Y2: maxY}
+			if len(shape.Points) >= 3 {
+				for _, p := range shape.Points {
+					if len(p) >= 2 {
+						box.Poly = append(box.Poly, [2]float64{p[0], p[1]})
+					}
+				}
+			}
+			boxes = append(boxes, box)
+		}
+	}
+	for _, lbl := range data.Labels {
+		if id, ok := classMap[lbl.Name]; ok {
+			boxes = append(boxes, YoloBox{Cls: id, X1: lbl.X1, Y1: lbl.Y1, X2: lbl.X2, Y2: lbl.Y2})
+		}
+	}
+	return boxes, nil
+}
+
+// FormatYoloLines 像素坐标框转为 YOLO 归一化文本行
+func FormatYoloLines(boxes []YoloBox, imgW, imgH int) []string {
+	var yoloLines []string
+	for _, b := range boxes {
+		w := b.X2 - b.X1
+		h := b.Y2 - b.Y1
+		cx := b.X1 + w/2.0
+		cy := b.Y1 + h/2.0
+		line := fmt.Sprintf("%d %.6f %.6f %.6f %.6f", b.Cls, cx/float64(imgW), cy/float64(imgH), w/float64(imgW), h/float64(imgH))
+		yoloLines = append(yoloLines, line)
+	}
+	return yoloLines
+}
+
+// ConvertJsonToYolo JSON转YOLO
+func ConvertJsonToYolo(jsonPath string, imgW, imgH int, classMap map[string]int) ([]string, error) {
+	boxes, err := LoadLabelMeBoxes(jsonPath, classMap)
+	if err != nil {
+		return nil, err
+	}
+	return FormatYoloLines(boxes, imgW, imgH), nil
+}
@@ -0,0 +1,161 @@
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ==================== 数据集清单与完整性校验 ====================
+
+// ManifestName 清单文件名，位于数据集根目录
+const ManifestName = "manifest.json"
+
+// ManifestEntry 一个输出文件的记录
+type ManifestEntry struct {
+	Path   string `json:"path"`             // 相对数据集根目录 (/ 分隔)；列表模式下为绝对路径
+	Kind   string `json:"kind"`             // image / label / meta
+	Split  string `json:"split,omitempty"`  // train / val / test
+	Size   int64  `json:"size"`             // 字节数
+	SHA256 string `json:"sha256"`           // 内容哈希
+	Source string `json:"source,omitempty"` // 源图片或源 JSON
+	// Quality 压缩所用 JPEG 质量，0 表示未重新编码
+	Quality int `json:"quality,omitempty"`
+}
+
+// Manifest 清单文件内容
+type Manifest struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Seed      int64           `json:"seed"` // 划分所用随机种子
+	Files     []ManifestEntry `json:"files"`
+}
+
+// ManifestRecorder 供多个 worker 并发登记输出文件
+type ManifestRecorder struct {
+	Seed    int64
+	mu      sync.Mutex
+	entries []ManifestEntry
+}
+
+// Record 登记内存中的数据，返回登记的条目
+func (m *ManifestRecorder) Record(rel, kind, split, source string, data []byte) ManifestEntry {
+	sum := sha256.Sum256(data)
+	return m.Add(ManifestEntry{Path: rel, Kind: kind, Split: split, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Source: source})
+}
+
+// RecordImage 登记压缩后的图片及所用质量
+func (m *ManifestRecorder) RecordImage(rel, split, source string, data []byte, quality int) ManifestEntry {
+	sum := sha256.Sum256(data)
+	return m.Add(ManifestEntry{Path: rel, Kind: "image", Split: split, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Source: source, Quality: quality})
+}
+
+// RecordFile 登记磁盘上的文件 (复制/链接的图片与源文件内容一致，直接哈希源文件)
+func (m *ManifestRecorder) RecordFile(rel, kind, split, source, hashPath string) (ManifestEntry, error) {
+	size, sum, err := HashFile(hashPath)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	return m.Add(ManifestEntry{Path: rel, Kind: kind, Split: split, Size: size, SHA256: sum, Source: source}), nil
+}
+
+// Add 登记已知大小与哈希的条目 (如增量模式下沿用的输出)
+func (m *ManifestRecorder) Add(e ManifestEntry) ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, e)
+	return e
+}
+
+// Build 生成按路径排序的清单 JSON
+func (m *ManifestRecorder) Build() ([]byte, error) {
+	m.mu.Lock()
+	files := append([]ManifestEntry(nil), m.entries...)
+	m.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return json.MarshalIndent(Manifest{CreatedAt: time.Now(), Seed: m.Seed, Files: files}, "", "  ")
+}
+
+// HashFile 计算文件大小与 SHA-256
+func HashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyReport 校验结果
+type VerifyReport struct {
+	Checked  int
+	Missing  []string
+	Modified []string
+	Extra    []string
+}
+
+// OK 是否完全一致
+func (r VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Modified) == 0 && len(r.Extra) == 0
+}
+
+// VerifyManifest 按清单重新哈希数据集目录，找出缺失、被修改和多出的文件
+func VerifyManifest(root string, progress func(done, total int)) (VerifyReport, error) {
+	var report VerifyReport
+	raw, err := os.ReadFile(filepath.Join(root, ManifestName))
+	if err != nil {
+		return report, fmt.Errorf("读取清单失败: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return report, fmt.Errorf("清单格式错误: %v", err)
+	}
+
+	known := make(map[string]bool)
+	for i, e := range m.Files {
+		p := e.Path
+		if !filepath.IsAbs(filepath.FromSlash(p)) {
+			p = filepath.Join(root, filepath.FromSlash(p))
+		}
+		known[filepath.Clean(p)] = true
+
+		size, sum, err := HashFile(p)
+		switch {
+		case os.IsNotExist(err):
+			report.Missing = append(report.Missing, e.Path)
+		case err != nil:
+			report.Modified = append(report.Modified, fmt.Sprintf("%s (%v)", e.Path, err))
+		case size != e.Size || sum != e.SHA256:
+			report.Modified = append(report.Modified, e.Path)
+		}
+		report.Checked++
+		if progress != nil {
+			progress(i+1, len(m.Files))
+		}
+	}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if p == filepath.Join(root, ManifestName) || known[filepath.Clean(p)] {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		report.Extra = append(report.Extra, filepath.ToSlash(rel))
+		return nil
+	})
+	return report, err
+}
@@ -0,0 +1,142 @@
+package dataset
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ==================== 输出与扫描 ====================
+
+// DirectCopy 直接复制
+func DirectCopy(src, dst string) error {
+	sourceFileStat, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !sourceFileStat.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destination, source)
+	if cErr := destination.Close(); err == nil {
+		err = cErr // 磁盘写满等错误可能到关闭时才出现
+	}
+	return err
+}
+
+// OutputMode 不压缩时图片的输出方式
+type OutputMode int
+
+const (
+	OutputCopy     OutputMode = iota // 复制
+	OutputHardlink                   // 硬链接，跨文件系统时退回复制
+	OutputSymlink                    // 符号链接，无权限时退回复制
+	OutputList                       // 不输出图片，只写 train.txt/val.txt 路径列表，标签写在源图片旁
+)
+
+var OutputModeNames = []string{"复制", "硬链接", "符号链接", "仅生成列表 (标签写在源图片旁)"}
+
+// 输出方式的英文键名
+var OutputModeKeys = []string{"copy", "hardlink", "symlink", "list"}
+
+// PlaceFile 按输出方式放置图片，返回实际使用的方式
+func PlaceFile(src, dst string, mode OutputMode) (OutputMode, error) {
+	// 先删除旧文件：上次运行留下的链接若被直接覆盖，会连带改写源图片
+	os.Remove(dst)
+	switch mode {
+	case OutputHardlink:
+		if err := os.Link(src, dst); err == nil {
+			return OutputHardlink, nil
+		}
+	case OutputSymlink:
+		if abs, err := filepath.Abs(src); err == nil {
+			if err := os.Symlink(abs, dst); err == nil {
+				return OutputSymlink, nil
+			}
+		}
+	}
+	return OutputCopy, DirectCopy(src, dst)
+}
+
+// ListLabelPath 列表模式下标签文件的位置，与 Ultralytics 的查找规则一致：
+// 路径中最后一个 /images/ 替换为 /labels/，扩展名替换为 .txt
+func ListLabelPath(imgPath string) string {
+	p := filepath.ToSlash(imgPath)
+	if i := strings.LastIndex(p, "/images/"); i >= 0 {
+		p = p[:i] + "/labels/" + p[i+len("/images/"):]
+	}
+	return filepath.FromSlash(strings.TrimSuffix(p, filepath.Ext(p)) + ".txt")
+}
+
+// ListOwnedLabels 输出目录的 splits.json 中记录的、由本工具写在源图片旁的标签 (/ 分隔)。
+// 列表模式只覆盖这些文件，其余已存在的 .txt 可能是用户自己的标注
+func ListOwnedLabels(out string) map[string]bool {
+	owned := make(map[string]bool)
+	prev, err := LoadAssignments(out)
+	if err != nil {
+		return owned
+	}
+	for _, a := range prev.Entries {
+		for _, p := range a.Labels {
+			if filepath.IsAbs(filepath.FromSlash(p)) {
+				owned[p] = true
+			}
+		}
+	}
+	return owned
+}
+
+// ScanSources 扫描数据源文件夹，配对图片与同名 JSON
+func ScanSources(dirs []string, logf func(string)) []FilePair {
+	var tasks []FilePair
+	for _, d := range dirs {
+		files, err := os.ReadDir(d)
+		if err != nil {
+			logf("读取错误: " + d)
+			continue
+		}
+		for _, f := range files {
+			if !f.IsDir() {
+				if IsImageExt(filepath.Ext(f.Name())) {
+					base := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+					tasks = append(tasks, FilePair{filepath.Join(d, f.Name()), filepath.Join(d, base+".json")})
+				}
+			}
+		}
+	}
+	return tasks
+}
+
+// UnmatchedJSONs 数据源中没有同名图片的 JSON 文件 (ScanSources 不会读到它们)
+func UnmatchedJSONs(dirs []string) []string {
+	var out []string
+	for _, d := range dirs {
+		files, err := os.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		bases := make(map[string]bool)
+		for _, f := range files {
+			if !f.IsDir() && IsImageExt(filepath.Ext(f.Name())) {
+				bases[strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))] = true
+			}
+		}
+		for _, f := range files {
+			if !f.IsDir() && strings.EqualFold(filepath.Ext(f.Name()), ".json") && !bases[strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))] {
+				out = append(out, filepath.Join(d, f.Name()))
+			}
+		}
+	}
+	return out
+}
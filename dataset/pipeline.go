@@ -0,0 +1,295 @@
+// Package dataset 把 LabelMe 标注的图片整理成 YOLO 数据集：扫描、划分、处理图片并写出标签与 data.yaml。
+// 图形界面与命令行都只是它的调用方
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// ==================== 构建流程 (扫描 / 划分 / 处理 / 写出) ====================
+
+// Options 一次构建的全部参数 (已解析)，通常由 Settings.Options 生成
+type Options struct {
+	Sources      []string // 数据源文件夹
+	Output       string   // 输出目录，或 .zip/.tar.gz
+	Classes      []string // 类别名，下标即类别 ID
+	RelativeYaml bool
+	YamlExtra    string
+
+	Split       SplitSpec
+	Seed        int64
+	Stratify    bool
+	Folds       int // 0 表示不做 K 折
+	Incremental bool
+	Group       GroupMode
+	GroupRe     *regexp.Regexp // Group 为 GroupRegex 时使用
+
+	Process    bool // 解码后重新编码 (压缩/转格式)，关闭时原样输出
+	MaxKB      int
+	OutputMode OutputMode
+	Resize     ResizeOptions
+	Alpha      AlphaOptions
+	TiffPages  TiffPageMode
+	Tile       TileOptions
+	Augment    AugmentOptions
+	Ignore     IgnoreOptions
+	Redact     RedactOptions
+
+	// DryRun 只扫描、配对和划分并给出报告 (Result.Plan)，不写入任何文件
+	DryRun bool
+	// Verbose 逐张输出压缩结果；关闭时只在结束时输出汇总 (超过上限的图片仍逐张提示)
+	Verbose bool
+	// Pause 暂停开关，为空时不可暂停
+	Pause *Pauser
+	// OnEvent 接收日志与进度，会被多个 worker 并发调用；为空时丢弃
+	OnEvent func(Event)
+}
+
+// EventKind 构建事件的类型
+type EventKind int
+
+const (
+	EventLog      EventKind = iota // 一行运行日志，见 Message
+	EventProgress                  // 一张源图片处理完毕，见 Done/Total/Source/Split
+	EventStatus                    // 监视模式的状态变化，见 Message
+)
+
+// Event 构建过程中发给调用方的通知
+type Event struct {
+	Kind    EventKind
+	Message string
+
+	Done, Total int    // 已完成 / 总的源图片数
+	Source      string // 刚完成的源图片路径
+	Split       string // 它所在的划分 (K 折时为折名)
+}
+
+func (o Options) emit(e Event) {
+	if o.OnEvent != nil {
+		o.OnEvent(e)
+	}
+}
+
+func (o Options) logf(msg string) { o.emit(Event{Kind: EventLog, Message: msg}) }
+
+// Result 构建结果统计
+type Result struct {
+	Images   int            // 源图片数
+	Splits   map[string]int // 各划分的源图片数
+	Skipped  int            // 增量模式下未变化而跳过的图片
+	Fallback int            // 无法建立链接、退回复制的文件
+	Errors   []FileError    // 处理失败的文件，按路径排序
+	Plan     *Plan          // 仅试运行时有值
+}
+
+// Validate 检查参数取值与相互冲突
+func (o Options) Validate() error {
+	if len(o.Sources) == 0 {
+		return fmt.Errorf("未添加数据源")
+	}
+	if o.Output == "" {
+		return fmt.Errorf("未选择输出目录")
+	}
+	if len(o.Classes) == 0 {
+		return fmt.Errorf("未填写类别")
+	}
+	if o.Process && o.MaxKB <= 0 {
+		return fmt.Errorf("MaxKB 必须是正整数")
+	}
+	if t := o.Tile; t.Size != 0 && (t.Size < 0 || t.Overlap < 0 || t.Overlap >= t.Size || t.MinVisible < 0 || t.MinVisible > 1) {
+		return fmt.Errorf("切片参数无效 (边长 > 重叠 >= 0，可见比例 0~1)")
+	}
+	if a := o.Augment; a.Copies < 0 || a.Jitter < 0 || a.Jitter > 1 || a.Crop < 0 || a.Crop > 1 {
+		return fmt.Errorf("增强参数无效 (份数 > 0，抖动与裁剪比例 0~1，裁剪 0 或 1 为不裁剪)")
+	}
+	if o.Augment.Copies > 0 && !o.Augment.HasTransform() {
+		return fmt.Errorf("增强未启用任何变换 (翻转、旋转、颜色抖动或裁剪)")
+	}
+	if o.Folds < 0 || o.Folds == 1 {
+		return fmt.Errorf("K 折数必须为 0 或不小于 2 的整数")
+	}
+	if o.Group == GroupRegex && o.GroupRe == nil {
+		return fmt.Errorf("未填写分组正则")
+	}
+	// 忽略区域与脱敏要改动像素，原样输出会把原图带出去
+	pixels := o.Process && o.OutputMode != OutputList
+	if len(o.Ignore.Labels) > 0 {
+		if !pixels {
+			return fmt.Errorf("忽略区域需要启用压缩/转格式，且不能使用列表模式")
+		}
+		for _, l := range o.Ignore.Labels {
+			if containsString(o.Classes, l) {
+				return fmt.Errorf("忽略标签 %q 同时出现在类别列表中", l)
+			}
+		}
+	}
+	if len(o.Redact.Labels) > 0 && !pixels {
+		return fmt.Errorf("脱敏需要启用压缩/转格式，且不能使用列表模式 (否则会直接输出原图)")
+	}
+	toArchive := IsArchivePath(o.Output)
+	if toArchive && o.Incremental {
+		return fmt.Errorf("增量更新需要输出到文件夹")
+	}
+	if toArchive && o.Folds > 0 {
+		return fmt.Errorf("K 折列表使用绝对路径，不能写入压缩包")
+	}
+	if toArchive && o.OutputMode == OutputList {
+		return fmt.Errorf("列表模式不输出图片，不能写入压缩包")
+	}
+	// 提前校验附加键，避免处理完才报错
+	_, err := BuildDataYaml(o.yamlOptions())
+	return err
+}
+
+func (o Options) yamlOptions() DataYamlOptions {
+	return DataYamlOptions{
+		Root:     o.Output,
+		Relative: o.RelativeYaml || IsArchivePath(o.Output), // 压缩包解压位置未知，只能用相对路径
+		Names:    o.Classes,
+		Extra:    o.YamlExtra,
+	}
+}
+
+// labelIDs 读取标注用的标签映射：类别之外的忽略/脱敏标签分配 nc 之后的 ID，只用于定位区域，不写入标签
+func (o Options) labelIDs() (clsMap, loadMap map[string]int, ignoreIDs, redactIDs map[int]bool) {
+	clsMap, loadMap = make(map[string]int), make(map[string]int)
+	for i, c := range o.Classes {
+		clsMap[c], loadMap[c] = i, i
+	}
+	extra := func(labels []string) map[int]bool {
+		ids := make(map[int]bool)
+		for _, l := range labels {
+			if _, ok := loadMap[l]; !ok {
+				loadMap[l] = len(loadMap)
+			}
+			ids[loadMap[l]] = true
+		}
+		return ids
+	}
+	return clsMap, loadMap, extra(o.Ignore.Labels), extra(o.Redact.Labels)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Run 执行一次完整构建：扫描、划分、逐张处理并写出标签、列表、data.yaml 与清单。
+// ctx 取消后不再开始新图片，进行中的图片在下一次写入前停下，
+// 随后删除本次运行写入的全部文件 (压缩包整个删除) 并返回 ctx.Err()
+func Run(ctx context.Context, o Options) (Result, error) {
+	var res Result
+	if err := o.Validate(); err != nil {
+		return res, err
+	}
+
+	o.logf(">>> 开始扫描...")
+	tasks := ScanSources(o.Sources, o.logf)
+	if len(tasks) == 0 {
+		return res, fmt.Errorf("未找到图片，请检查路径")
+	}
+	ShuffleTasks(tasks, o.Seed)
+	o.logf(fmt.Sprintf("随机种子: %d", o.Seed))
+
+	plan, err := o.PlanSplits(tasks)
+	if err != nil {
+		return res, err
+	}
+	o.logNotes()
+	res.Images, res.Splits = len(tasks), plan.Counts()
+
+	if o.DryRun {
+		o.logf(">>> 试运行：统计中，不会写入任何文件")
+		_, loadMap, _, _ := o.labelIDs()
+		done := 0
+		res.Plan = o.plan(ctx, tasks, plan.Subsets, plan.Names, loadMap, func(src, split string) {
+			done++
+			o.emit(Event{Kind: EventProgress, Done: done, Total: len(tasks), Source: src, Split: split})
+		})
+		if err := ctx.Err(); err != nil {
+			o.logf(">>> 已取消")
+			return Result{}, err
+		}
+		for _, line := range res.Plan.Lines() {
+			o.logf(line)
+		}
+		o.logf(">>> 试运行完成")
+		return res, nil
+	}
+
+	b, err := newBuilder(o, plan, len(tasks))
+	if err != nil {
+		return Result{}, err
+	}
+	b.run(ctx, tasks)
+	if err := ctx.Err(); err != nil {
+		b.sink.Discard()
+		o.logf(fmt.Sprintf(">>> 已取消 (完成 %d/%d 张)，已删除本次写入的文件", atomic.LoadInt32(&b.done), len(tasks)))
+		return Result{}, err
+	}
+	b.finish(tasks)
+
+	res.Skipped, res.Fallback = int(b.skipped), int(b.fallback)
+	res.Errors = b.failures.sorted()
+	if n := len(res.Errors); n > 0 {
+		o.logf(fmt.Sprintf(">>> 完成，但有 %d 处失败", n))
+		return res, nil
+	}
+	o.logf(">>> 完成！")
+	return res, nil
+}
+
+// logNotes 开始处理前说明哪些设置生效、哪些因相互冲突而不生效
+func (o Options) logNotes() {
+	switch {
+	case o.OutputMode == OutputList:
+		o.logf("列表模式：不输出图片，标签写在源图片旁 (路径含 /images/ 时写入对应的 /labels/)")
+	case o.Process && o.OutputMode != OutputCopy:
+		o.logf("提示：启用压缩时图片需重新编码，输出方式 [" + OutputModeNames[o.OutputMode] + "] 不生效")
+	case IsArchivePath(o.Output) && o.OutputMode != OutputCopy:
+		o.logf("提示：压缩包内无法建立链接，图片将直接写入压缩包")
+	}
+	pixels := o.Process && o.OutputMode != OutputList
+	if (o.Resize.Mode != ResizeNone || o.Tile.Size > 0) && !pixels {
+		o.logf("提示：尺寸调整与切片需要启用压缩/转格式，本次不生效")
+	}
+	if pixels {
+		if o.Alpha.Mode == AlphaKeepPNG {
+			o.logf("透明通道: 含透明的图片保留为无损 PNG，不受 MaxKB 质量压缩影响")
+		} else {
+			bg := o.Alpha.Background
+			o.logf(fmt.Sprintf("透明通道: 铺底色 #%02X%02X%02X 后转 JPEG", bg.R, bg.G, bg.B))
+		}
+	}
+	if o.TiffPages == TiffAllPages && !pixels {
+		o.logf("提示：TIFF 分页输出需要启用压缩/转格式，本次按原文件输出")
+	}
+	if len(o.Ignore.Labels) > 0 {
+		o.logf(fmt.Sprintf("忽略区域: %s，填充中性色并删除大部分落在其中的框", strings.Join(o.Ignore.Labels, ", ")))
+	}
+	if len(o.Redact.Labels) > 0 {
+		action := "保留标注"
+		if o.Redact.Drop {
+			action = "删除标注"
+		}
+		o.logf(fmt.Sprintf("隐私脱敏: %s [%s]，%s", strings.Join(o.Redact.Labels, ", "), RedactModeNames[o.Redact.Mode], action))
+	}
+	if o.Augment.Copies > 0 {
+		switch {
+		case !pixels:
+			o.logf("提示：离线增强需要启用压缩/转格式，本次不生效")
+		case o.Folds > 0:
+			o.logf("提示：K 折模式下每张图都会轮流进入验证集，不做离线增强")
+		default:
+			o.logf(fmt.Sprintf("离线增强: 训练集每张图额外生成 %d 份", o.Augment.Copies))
+		}
+	}
+}
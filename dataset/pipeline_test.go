@@ -0,0 +1,120 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSource 在 dir 下生成 n 张 PNG 及对应的 LabelMe 标注 (一个 cat 框、一个 dog 多边形)
+func writeSource(t *testing.T, dir string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("img%02d", i))
+		f, err := os.Create(name + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = png.Encode(f, noiseImage(80, 60, 255))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		js := `{"imageWidth":80,"imageHeight":60,"shapes":[` +
+			`{"label":"cat","points":[[5,5],[30,40]]},` +
+			`{"label":"dog","points":[[40,10],[70,10],[55,50]]}]}`
+		if err := os.WriteFile(name+".json", []byte(js), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunVerifyManifest(t *testing.T) {
+	root := t.TempDir()
+	src, out := filepath.Join(root, "src"), filepath.Join(root, "out")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeSource(t, src, 10)
+
+	s := DefaultSettings()
+	s.Sources, s.Output, s.Classes, s.Seed, s.Incremental = []string{src}, out, "cat,dog", "7", true
+	opts, err := s.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func() Result {
+		t.Helper()
+		res, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Errors) > 0 {
+			t.Fatalf("处理失败: %+v", res.Errors)
+		}
+		return res
+	}
+	verify := func() VerifyReport {
+		t.Helper()
+		report, err := VerifyManifest(out, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	res := run()
+	if want := map[string]int{"train": 8, "val": 2}; res.Images != 10 || !reflect.DeepEqual(res.Splits, want) {
+		t.Fatalf("Images/Splits = %d/%v, want 10/%v", res.Images, res.Splits, want)
+	}
+	for _, p := range []string{"data.yaml", AssignmentName, ManifestName} {
+		if _, err := os.Stat(filepath.Join(out, p)); err != nil {
+			t.Errorf("缺少 %s: %v", p, err)
+		}
+	}
+	labels, _ := filepath.Glob(filepath.Join(out, "labels", "*", "*.txt"))
+	if len(labels) != 10 {
+		t.Errorf("生成 %d 个标签, want 10", len(labels))
+	}
+	// 10 张图片与 10 个标签，另有 data.yaml 等
+	if report := verify(); !report.OK() || report.Checked < 20 {
+		t.Fatalf("首次构建校验: %+v", report)
+	}
+
+	// 增量重建沿用上次的输出，清单不变
+	if res := run(); res.Skipped != 10 {
+		t.Errorf("增量重建跳过 %d 张, want 10", res.Skipped)
+	}
+	if report := verify(); !report.OK() {
+		t.Fatalf("增量重建后校验: %+v", report)
+	}
+
+	// 改动、删除与新增的文件都应被找出
+	images, _ := filepath.Glob(filepath.Join(out, "images", "train", "*.jpg"))
+	if len(images) != 8 {
+		t.Fatalf("训练集 %d 张图片, want 8", len(images))
+	}
+	rel := func(p string) string {
+		r, _ := filepath.Rel(out, p)
+		return filepath.ToSlash(r)
+	}
+	if err := os.WriteFile(labels[0], []byte("0 0.5 0.5 1 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(images[0]); err != nil {
+		t.Fatal(err)
+	}
+	extra := filepath.Join(out, "images", "train", "extra.jpg")
+	if err := os.WriteFile(extra, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report := verify()
+	want := VerifyReport{Checked: report.Checked, Missing: []string{rel(images[0])}, Modified: []string{rel(labels[0])}, Extra: []string{rel(extra)}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("改动后校验 = %+v, want %+v", report, want)
+	}
+}
@@ -0,0 +1,237 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ==================== 试运行 (只规划，不写入) ====================
+
+const planListLimit = 10 // 报告中每类问题最多列出的条目数
+
+// Collision 多张源图片会写到同一个输出文件名
+type Collision struct {
+	Output  string   // 输出路径 (启用压缩时不含扩展名)
+	Sources []string // 冲突的源图片
+}
+
+// Plan 试运行的结果：扫描、配对、类别映射与划分都已完成，但没有写入任何文件
+type Plan struct {
+	Splits       []string       // 划分名，顺序同报告
+	SplitCounts  map[string]int // 各划分的源图片数
+	ClassTable   []string       // 各类别在各划分中的图片数 (已排版)
+	ClassBoxes   map[string]int // 各类别的框数
+	Unlabeled    int            // 没有 JSON 的图片
+	BadJSON      []string       // 读取失败的 JSON
+	Unmatched    []string       // 没有同名图片的 JSON
+	Unknown      map[string]int // 不在类别、忽略与脱敏列表中的标签及其出现次数
+	Collisions   []Collision
+	Foreign      []string // 列表模式下已存在、不是本工具生成的标签，不会被覆盖
+	OutputImages int      // 预计输出的图片数 (含切片与增强)
+	OutputBytes  int64    // 预计新增的图片字节数，链接与列表模式不计
+}
+
+// plan 统计试运行报告；names 为划分名，subsets[i] 为 tasks[i] 的划分，ctx 取消时提前返回
+func (o Options) plan(ctx context.Context, tasks []FilePair, subsets, names []string, loadMap map[string]int, progress func(src, split string)) *Plan {
+	p := &Plan{
+		Splits:      names,
+		SplitCounts: make(map[string]int),
+		ClassBoxes:  make(map[string]int),
+		Unknown:     make(map[string]int),
+		Unmatched:   UnmatchedJSONs(o.Sources),
+	}
+	pixels := o.Process && o.OutputMode != OutputList
+	var owned map[string]bool
+	if o.OutputMode == OutputList {
+		owned = ListOwnedLabels(o.Output)
+	}
+	classes := make([][]int, len(tasks))
+	outputs := make(map[string][]string)
+	for i, t := range tasks {
+		if ctx.Err() != nil {
+			return p
+		}
+		p.SplitCounts[subsets[i]]++
+		labels, labelErr := LabelMeNames(t.JsonPath)
+		if os.IsNotExist(labelErr) {
+			p.Unlabeled++
+		} else if labelErr != nil {
+			p.BadJSON = append(p.BadJSON, t.JsonPath)
+		} else {
+			for _, l := range labels {
+				id, ok := loadMap[l]
+				switch {
+				case !ok:
+					p.Unknown[l]++
+				case id < len(o.Classes):
+					p.ClassBoxes[l]++
+					classes[i] = append(classes[i], id)
+				}
+			}
+		}
+
+		if owned != nil && labelErr == nil {
+			if abs, err := filepath.Abs(t.ImgPath); err == nil {
+				label := ListLabelPath(abs)
+				if _, err := os.Stat(label); err == nil && !owned[filepath.ToSlash(label)] {
+					p.Foreign = append(p.Foreign, label)
+				}
+			}
+		}
+
+		// 输出文件名：启用压缩时扩展名由编码结果决定，只比较主名
+		if o.OutputMode != OutputList {
+			dir := subsets[i]
+			if o.Folds > 0 {
+				dir = "all"
+			}
+			name := strings.TrimSuffix(filepath.Base(t.ImgPath), filepath.Ext(t.ImgPath))
+			if !pixels {
+				name += filepath.Ext(t.ImgPath)
+			}
+			key := "images/" + dir + "/" + name
+			outputs[key] = append(outputs[key], t.ImgPath)
+		}
+
+		n, size := o.estimateOutput(t.ImgPath, subsets[i], pixels)
+		p.OutputImages += n
+		p.OutputBytes += size
+		progress(t.ImgPath, subsets[i])
+	}
+	p.ClassTable = SplitClassTable(classes, subsets, names, o.Classes)
+	for out, srcs := range outputs {
+		if len(srcs) > 1 {
+			sort.Strings(srcs)
+			p.Collisions = append(p.Collisions, Collision{Output: out, Sources: srcs})
+		}
+	}
+	sort.Slice(p.Collisions, func(i, j int) bool { return p.Collisions[i].Output < p.Collisions[j].Output })
+	return p
+}
+
+// estimateOutput 粗略估算一张源图片产生的输出图片数与字节数：
+// 重新编码时每张不超过 MaxKB，切片按面积比例分摊原图大小；不考虑缩放与 TIFF 分页
+func (o Options) estimateOutput(imgPath, subset string, pixels bool) (int, int64) {
+	st, err := os.Stat(imgPath)
+	if err != nil {
+		return 0, 0
+	}
+	size := st.Size()
+	if !pixels {
+		switch {
+		case o.OutputMode == OutputList:
+			return 0, 0
+		case o.OutputMode != OutputCopy && !IsArchivePath(o.Output):
+			return 1, 0
+		}
+		return 1, size
+	}
+	n := 1
+	if o.Tile.Size > 0 {
+		if f, err := os.Open(imgPath); err == nil {
+			cfg, _, err := image.DecodeConfig(f)
+			f.Close()
+			if err == nil && cfg.Width > 0 && cfg.Height > 0 {
+				n = len(tileStarts(cfg.Width, o.Tile.Size, o.Tile.Overlap)) * len(tileStarts(cfg.Height, o.Tile.Size, o.Tile.Overlap))
+				w, h := min(cfg.Width, o.Tile.Size), min(cfg.Height, o.Tile.Size)
+				size = size * int64(w*h) / int64(cfg.Width*cfg.Height)
+			}
+		}
+	}
+	if o.Augment.Copies > 0 && subset == "train" && o.Folds == 0 {
+		n *= 1 + o.Augment.Copies
+	}
+	return n, int64(n) * min(size, int64(o.MaxKB)*1024)
+}
+
+// Lines 把报告排版成文本行
+func (p *Plan) Lines() []string {
+	var parts []string
+	for _, name := range p.Splits {
+		parts = append(parts, fmt.Sprintf("%s %d", name, p.SplitCounts[name]))
+	}
+	lines := []string{"划分: " + strings.Join(parts, " / "), "各类别图片数:"}
+	lines = append(lines, p.ClassTable...)
+
+	var boxes []string
+	for _, name := range sortedKeys(p.ClassBoxes) {
+		boxes = append(boxes, fmt.Sprintf("%s %d", name, p.ClassBoxes[name]))
+	}
+	if len(boxes) > 0 {
+		lines = append(lines, "各类别框数: "+strings.Join(boxes, ", "))
+	}
+	lines = append(lines, fmt.Sprintf("无标注图片: %d", p.Unlabeled))
+
+	list := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d", title, len(items)))
+		for i, s := range items {
+			if i == planListLimit {
+				lines = append(lines, fmt.Sprintf("  ... 另有 %d 项", len(items)-planListLimit))
+				break
+			}
+			lines = append(lines, "  "+s)
+		}
+	}
+	list("标注读取失败", p.BadJSON)
+	list("没有同名图片的 JSON", p.Unmatched)
+
+	// 未知标签按出现次数从多到少
+	unknown := sortedKeys(p.Unknown)
+	sort.SliceStable(unknown, func(i, j int) bool { return p.Unknown[unknown[i]] > p.Unknown[unknown[j]] })
+	for i, l := range unknown {
+		unknown[i] = fmt.Sprintf("%q × %d", l, p.Unknown[l])
+	}
+	list("不在类别列表中的标签 (将被忽略)", unknown)
+
+	var collisions []string
+	for _, c := range p.Collisions {
+		collisions = append(collisions, c.Output+" ← "+strings.Join(c.Sources, ", "))
+	}
+	list("输出重名 (后写入的会覆盖先写入的)", collisions)
+	sort.Strings(p.Foreign)
+	list("已有不是本工具生成的标签 (不会覆盖，对应图片不列入列表)", p.Foreign)
+
+	lines = append(lines, fmt.Sprintf("预计输出图片 %d 张，约 %s (估算，不含标签)", p.OutputImages, formatSize(p.OutputBytes)))
+	return lines
+}
+
+// Summary 一段话的概要，供对话框显示
+func (p *Plan) Summary() string {
+	total := 0
+	for _, n := range p.SplitCounts {
+		total += n
+	}
+	s := fmt.Sprintf("共 %d 张，预计输出 %d 张，约 %s\n标注读取失败 %d，无图片的 JSON %d，未知标签 %d 种，输出重名 %d 处",
+		total, p.OutputImages, formatSize(p.OutputBytes), len(p.BadJSON), len(p.Unmatched), len(p.Unknown), len(p.Collisions))
+	if len(p.Foreign) > 0 {
+		s += fmt.Sprintf("，拒绝覆盖的已有标签 %d 个", len(p.Foreign))
+	}
+	return s
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.2f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+}
@@ -0,0 +1,152 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ==================== 隐私脱敏 (模糊 / 马赛克 / 填充) ====================
+
+// RedactMode 脱敏方式
+type RedactMode int
+
+const (
+	RedactBlur     RedactMode = iota // 强模糊 (缩小后双线性放大)
+	RedactPixelate                   // 马赛克
+	RedactFill                       // 纯色填充
+)
+
+var RedactModeNames = []string{"模糊", "马赛克", "纯色填充"}
+
+// 脱敏方式的英文键名
+var RedactModeKeys = []string{"blur", "pixelate", "fill"}
+
+// RedactOptions 脱敏参数
+type RedactOptions struct {
+	Mode   RedactMode
+	Labels []string   // 需脱敏的 LabelMe 标签，可以不在类别列表中
+	Drop   bool       // 同时从 YOLO 标签中删除这些标注
+	Fill   color.RGBA // RedactFill 方式的填充色
+}
+
+// 脱敏区域缩小到的最大边长，越小越模糊
+const redactBlocks = 12
+
+// RedactRegions 在图片上对区域做脱敏，多边形标注只处理多边形内部；返回新图片，不改动原图
+func RedactRegions(img image.Image, regions []YoloBox, mode RedactMode, fill color.RGBA) *image.RGBA {
+	dst := toRGBA(img)
+	bounds := dst.Bounds()
+	for _, r := range regions {
+		rect := image.Rect(int(math.Floor(r.X1)), int(math.Floor(r.Y1)), int(math.Ceil(r.X2)), int(math.Ceil(r.Y2))).Intersect(bounds)
+		if rect.Empty() {
+			continue
+		}
+		var patch image.Image
+		switch mode {
+		case RedactFill:
+			patch = &image.Uniform{fill}
+		default:
+			long := math.Max(float64(rect.Dx()), float64(rect.Dy()))
+			s := math.Min(1, redactBlocks/long)
+			sw, sh := int(math.Max(1, math.Round(float64(rect.Dx())*s))), int(math.Max(1, math.Round(float64(rect.Dy())*s)))
+			small := image.NewRGBA(image.Rect(0, 0, sw, sh))
+			xdraw.ApproxBiLinear.Scale(small, small.Bounds(), dst, rect, xdraw.Src, nil)
+			up := image.NewRGBA(rect)
+			if mode == RedactPixelate {
+				xdraw.NearestNeighbor.Scale(up, rect, small, small.Bounds(), xdraw.Src, nil)
+			} else {
+				xdraw.BiLinear.Scale(up, rect, small, small.Bounds(), xdraw.Src, nil)
+			}
+			patch = up
+		}
+		if r.Poly != nil {
+			// 逐像素替换多边形内部：draw.Src 带遮罩时会把遮罩外的像素清成透明，
+			// draw.Over 又会让半透明图片的原像素透出来
+			mask := polygonMask(r.Poly, rect)
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				for x := rect.Min.X; x < rect.Max.X; x++ {
+					if mask.AlphaAt(x, y).A != 0 {
+						dst.Set(x, y, patch.At(x, y))
+					}
+				}
+			}
+		} else {
+			draw.Draw(dst, rect, patch, rect.Min, draw.Src)
+		}
+	}
+	return dst
+}
+
+// polygonMask 多边形内部 (按像素中心判断) 为不透明的遮罩
+func polygonMask(poly [][2]float64, rect image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if pointInPolygon(float64(x)+0.5, float64(y)+0.5, poly) {
+				mask.SetAlpha(x, y, color.Alpha{255})
+			}
+		}
+	}
+	return mask
+}
+
+// pointInPolygon 射线法判断点是否在多边形内
+func pointInPolygon(x, y float64, poly [][2]float64) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// SplitBoxes 按类别 ID 把框分成两组：ids 中的与其余的
+func SplitBoxes(boxes []YoloBox, ids map[int]bool) (matched, rest []YoloBox) {
+	for _, b := range boxes {
+		if ids[b.Cls] {
+			matched = append(matched, b)
+		} else {
+			rest = append(rest, b)
+		}
+	}
+	return matched, rest
+}
+
+// ==================== 忽略区域 ====================
+
+// 框落在忽略区域内的面积占比超过此值时删除
+const ignoreCoverRatio = 0.5
+
+// IgnoreOptions 忽略区域参数
+type IgnoreOptions struct {
+	Labels []string // 忽略区域的 LabelMe 标签，不能与类别重名
+	Fill   color.RGBA
+}
+
+// DropCovered 删除大部分面积落在忽略区域内的框
+func DropCovered(boxes, regions []YoloBox) []YoloBox {
+	var kept []YoloBox
+	for _, b := range boxes {
+		area := (b.X2 - b.X1) * (b.Y2 - b.Y1)
+		covered := 0.0
+		for _, r := range regions {
+			poly := r.Poly
+			if poly == nil {
+				poly = [][2]float64{{r.X1, r.Y1}, {r.X2, r.Y1}, {r.X2, r.Y2}, {r.X1, r.Y2}}
+			}
+			if clipped := clipPolygon(poly, b.X1, b.Y1, b.X2, b.Y2); len(clipped) >= 3 {
+				covered += polygonArea(clipped)
+			}
+		}
+		if area <= 0 || covered/area <= ignoreCoverRatio {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,84 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkerImage 1 像素黑白棋盘格，模糊或马赛克后会变成灰色
+func checkerImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x+y)%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestRedactRegions(t *testing.T) {
+	fill := color.RGBA{0, 255, 0, 255}
+	rect := YoloBox{X1: 4, Y1: 4, X2: 28, Y2: 28}
+	tri := YoloBox{X1: 4, Y1: 4, X2: 28, Y2: 28, Poly: [][2]float64{{4, 4}, {28, 4}, {4, 28}}}
+	tests := []struct {
+		name      string
+		region    YoloBox
+		mode      RedactMode
+		changed   []image.Point // 应被改动的像素
+		unchanged []image.Point // 应保持原样的像素
+	}{
+		{"纯色填充矩形", rect, RedactFill, []image.Point{{4, 4}, {27, 27}, {15, 15}}, []image.Point{{3, 3}, {28, 28}, {0, 39}}},
+		{"纯色填充多边形", tri, RedactFill, []image.Point{{5, 5}, {20, 5}}, []image.Point{{24, 24}, {3, 3}}},
+		{"模糊", rect, RedactBlur, []image.Point{{10, 10}, {17, 20}}, []image.Point{{2, 2}, {35, 35}}},
+		{"马赛克", rect, RedactPixelate, []image.Point{{10, 10}, {17, 20}}, []image.Point{{2, 2}, {35, 35}}},
+		{"超出图片的区域", YoloBox{X1: 35, Y1: 35, X2: 60, Y2: 60}, RedactFill, []image.Point{{39, 39}}, []image.Point{{34, 34}}},
+	}
+	for _, tt := range tests {
+		src := checkerImage(40, 40)
+		orig := append([]uint8(nil), src.Pix...)
+		out := RedactRegions(src, []YoloBox{tt.region}, tt.mode, fill)
+		if string(src.Pix) != string(orig) {
+			t.Errorf("%s: 改动了原图", tt.name)
+		}
+		for _, p := range tt.changed {
+			got, was := out.RGBAAt(p.X, p.Y), src.RGBAAt(p.X, p.Y)
+			if tt.mode == RedactFill && got != fill || tt.mode != RedactFill && got == was {
+				t.Errorf("%s: (%v) 未脱敏: %v", tt.name, p, got)
+			}
+		}
+		for _, p := range tt.unchanged {
+			if got, was := out.RGBAAt(p.X, p.Y), src.RGBAAt(p.X, p.Y); got != was {
+				t.Errorf("%s: (%v) 被改动: %v, want %v", tt.name, p, got, was)
+			}
+		}
+	}
+}
+
+func TestDropCovered(t *testing.T) {
+	box := YoloBox{Cls: 0, X1: 0, Y1: 0, X2: 10, Y2: 10}
+	tests := []struct {
+		name    string
+		regions []YoloBox
+		kept    bool
+	}{
+		{"无忽略区域", nil, true},
+		{"完全覆盖", []YoloBox{{X1: -5, Y1: -5, X2: 20, Y2: 20}}, false},
+		{"覆盖一半不删", []YoloBox{{X1: 5, Y1: 0, X2: 20, Y2: 10}}, true},
+		{"覆盖六成", []YoloBox{{X1: 4, Y1: 0, X2: 20, Y2: 10}}, false},
+		{"多个区域累计", []YoloBox{{X1: 0, Y1: 0, X2: 2, Y2: 10}, {X1: 8, Y1: 0, X2: 10, Y2: 10}}, true},
+		{"多个区域累计超过一半", []YoloBox{{X1: 0, Y1: 0, X2: 3, Y2: 10}, {X1: 7, Y1: 0, X2: 10, Y2: 10}}, false},
+		{"多边形按面积计算", []YoloBox{{X1: 0, Y1: 0, X2: 10, Y2: 10, Poly: [][2]float64{{0, 0}, {10, 0}, {0, 10}}}}, true},
+		{"多边形覆盖大部分", []YoloBox{{X1: 0, Y1: 0, X2: 20, Y2: 20, Poly: [][2]float64{{0, 0}, {20, 0}, {0, 20}}}}, false},
+	}
+	for _, tt := range tests {
+		got := DropCovered([]YoloBox{box}, tt.regions)
+		if (len(got) == 1) != tt.kept {
+			t.Errorf("%s: 保留 %d 个框, want kept=%v", tt.name, len(got), tt.kept)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ==================== 失败记录 ====================
+
+// Stage 出错的处理阶段
+type Stage string
+
+const (
+	StageRead     Stage = "read"     // 读取源文件
+	StageLabel    Stage = "label"    // 解析标注 JSON
+	StageDecode   Stage = "decode"   // 解码图片
+	StageCompress Stage = "compress" // 压缩/转格式
+	StageWrite    Stage = "write"    // 写出图片、标签或列表
+	StagePanic    Stage = "panic"    // 处理时发生异常
+)
+
+// StageNames 阶段的界面显示名
+var StageNames = map[Stage]string{
+	StageRead:     "读取",
+	StageLabel:    "标注",
+	StageDecode:   "解码",
+	StageCompress: "压缩",
+	StageWrite:    "写入",
+	StagePanic:    "异常",
+}
+
+// FileError 一条失败记录
+type FileError struct {
+	Path  string `json:"path"` // 源文件路径，或输出内的相对路径
+	Stage Stage  `json:"stage"`
+	Err   string `json:"error"`
+}
+
+func (e FileError) String() string {
+	return fmt.Sprintf("%s [%s] %s", e.Path, StageNames[e.Stage], e.Err)
+}
+
+// errorList 多个 worker 共用的失败记录
+type errorList struct {
+	mu    sync.Mutex
+	items []FileError
+}
+
+func (l *errorList) add(path string, stage Stage, err error) FileError {
+	e := FileError{Path: path, Stage: stage, Err: err.Error()}
+	l.mu.Lock()
+	l.items = append(l.items, e)
+	l.mu.Unlock()
+	return e
+}
+
+// sorted 按路径、阶段排序后的副本
+func (l *errorList) sorted() []FileError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := append([]FileError(nil), l.items...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Stage < out[j].Stage
+	})
+	return out
+}
+
+// SaveErrorReport 导出失败记录：.json 写 JSON 数组，其余写 CSV (带 UTF-8 BOM，Excel 可直接打开)
+func SaveErrorReport(path string, errs []FileError) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = writeErrorsJSON(f, errs)
+	} else {
+		err = writeErrorsCSV(f, errs)
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+func writeErrorsJSON(w io.Writer, errs []FileError) error {
+	if errs == nil {
+		errs = []FileError{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(errs)
+}
+
+func writeErrorsCSV(w io.Writer, errs []FileError) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "stage", "error"})
+	for _, e := range errs {
+		cw.Write([]string{e.Path, string(e.Stage), e.Err})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,129 @@
+package dataset
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ==================== 尺寸调整 (缩放 / Letterbox) ====================
+
+// ResizeMode 输出尺寸调整方式
+type ResizeMode int
+
+const (
+	ResizeNone      ResizeMode = iota // 不调整
+	ResizeMaxSide                     // 最长边不超过 Width，保持比例，只缩小
+	ResizeExact                       // 拉伸到 Width x Height
+	ResizeLetterbox                   // 保持比例缩放到 Width x Height 内，四周填充 Pad
+)
+
+var ResizeModeNames = []string{"不缩放", "限制最长边", "拉伸到指定尺寸", "Letterbox 填充"}
+
+// 尺寸调整方式的英文键名
+var ResizeModeKeys = []string{"none", "max", "exact", "letterbox"}
+
+// ResizeOptions 尺寸调整参数
+type ResizeOptions struct {
+	Mode          ResizeMode
+	Width, Height int
+	Pad           color.RGBA
+}
+
+// Transform 原图像素坐标到输出图像素坐标的变换：x' = x*ScaleX + OffX
+type Transform struct {
+	ScaleX, ScaleY float64
+	OffX, OffY     float64
+	W, H           int // 输出尺寸
+}
+
+// IdentityTransform 不改变尺寸时的变换
+func IdentityTransform(w, h int) Transform {
+	return Transform{ScaleX: 1, ScaleY: 1, W: w, H: h}
+}
+
+// Box 变换一个标注框 (含多边形)
+func (t Transform) Box(b YoloBox) YoloBox {
+	return b.MapPoints(func(x, y float64) (float64, float64) {
+		return x*t.ScaleX + t.OffX, y*t.ScaleY + t.OffY
+	})
+}
+
+// Boxes 变换一组标注框
+func (t Transform) Boxes(bs []YoloBox) []YoloBox {
+	out := make([]YoloBox, len(bs))
+	for i, b := range bs {
+		out[i] = t.Box(b)
+	}
+	return out
+}
+
+// ApplyResize 按参数调整图片尺寸，返回新图片与坐标变换；无需调整时原样返回
+func ApplyResize(img image.Image, opt ResizeOptions) (image.Image, Transform) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	switch opt.Mode {
+	case ResizeMaxSide:
+		long := math.Max(float64(w), float64(h))
+		if opt.Width <= 0 || long <= float64(opt.Width) {
+			break
+		}
+		s := float64(opt.Width) / long
+		nw, nh := int(math.Round(float64(w)*s)), int(math.Round(float64(h)*s))
+		return ResizeImage(img, nw, nh), Transform{ScaleX: float64(nw) / float64(w), ScaleY: float64(nh) / float64(h), W: nw, H: nh}
+	case ResizeExact:
+		if opt.Width == w && opt.Height == h {
+			break
+		}
+		return ResizeImage(img, opt.Width, opt.Height), Transform{ScaleX: float64(opt.Width) / float64(w), ScaleY: float64(opt.Height) / float64(h), W: opt.Width, H: opt.Height}
+	case ResizeLetterbox:
+		s := math.Min(float64(opt.Width)/float64(w), float64(opt.Height)/float64(h))
+		nw, nh := int(math.Round(float64(w)*s)), int(math.Round(float64(h)*s))
+		offX, offY := (opt.Width-nw)/2, (opt.Height-nh)/2
+		dst := image.NewRGBA(image.Rect(0, 0, opt.Width, opt.Height))
+		draw.Draw(dst, dst.Bounds(), &image.Uniform{opt.Pad}, image.Point{}, draw.Src)
+		xdraw.CatmullRom.Scale(dst, image.Rect(offX, offY, offX+nw, offY+nh), img, b, xdraw.Src, nil)
+		return dst, Transform{
+			ScaleX: float64(nw) / float64(w), ScaleY: float64(nh) / float64(h),
+			OffX: float64(offX), OffY: float64(offY), W: opt.Width, H: opt.Height,
+		}
+	}
+	return img, IdentityTransform(w, h)
+}
+
+// ParseSize 解析 "640" 或 "640x480"
+func ParseSize(text string) (int, int, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	parts := strings.Split(strings.ReplaceAll(text, "*", "x"), "x")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("尺寸格式应为 640 或 640x480")
+	}
+	w, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || w <= 0 {
+		return 0, 0, fmt.Errorf("尺寸格式应为 640 或 640x480")
+	}
+	h := w
+	if len(parts) == 2 {
+		h, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || h <= 0 {
+			return 0, 0, fmt.Errorf("尺寸格式应为 640 或 640x480")
+		}
+	}
+	return w, h, nil
+}
+
+// ParseHexColor 解析 #RRGGBB 颜色
+func ParseHexColor(text string) (color.RGBA, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "#")
+	v, err := strconv.ParseUint(text, 16, 32)
+	if err != nil || len(text) != 6 {
+		return color.RGBA{}, fmt.Errorf("颜色格式应为 #RRGGBB")
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
@@ -0,0 +1,68 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestApplyResize(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	gray := color.RGBA{114, 114, 114, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(src, src.Bounds(), &image.Uniform{red}, image.Point{}, draw.Src)
+	box := YoloBox{Cls: 0, X1: 20, Y1: 10, X2: 100, Y2: 90}
+	tri := YoloBox{Cls: 1, X1: 0, Y1: 0, X2: 200, Y2: 100, Poly: [][2]float64{{0, 0}, {200, 0}, {0, 100}}}
+	tests := []struct {
+		name    string
+		opt     ResizeOptions
+		w, h    int
+		box     YoloBox // box 变换后的外接框
+		tri     YoloBox // tri 变换后的外接框
+		padAt   image.Point
+		padded  bool // padAt 处应为填充色
+		samePtr bool // 无需调整时原样返回
+	}{
+		{"不缩放", ResizeOptions{Mode: ResizeNone}, 200, 100, box, tri, image.Point{}, false, true},
+		{"最长边已满足", ResizeOptions{Mode: ResizeMaxSide, Width: 400}, 200, 100, box, tri, image.Point{}, false, true},
+		{"限制最长边", ResizeOptions{Mode: ResizeMaxSide, Width: 100}, 100, 50,
+			YoloBox{X1: 10, Y1: 5, X2: 50, Y2: 45}, YoloBox{X2: 100, Y2: 50}, image.Point{}, false, false},
+		{"拉伸", ResizeOptions{Mode: ResizeExact, Width: 100, Height: 200}, 100, 200,
+			YoloBox{X1: 10, Y1: 20, X2: 50, Y2: 180}, YoloBox{X2: 100, Y2: 200}, image.Point{}, false, false},
+		{"Letterbox 上下填充", ResizeOptions{Mode: ResizeLetterbox, Width: 100, Height: 100, Pad: gray}, 100, 100,
+			YoloBox{X1: 10, Y1: 30, X2: 50, Y2: 70}, YoloBox{Y1: 25, X2: 100, Y2: 75}, image.Pt(50, 10), true, false},
+		{"Letterbox 左右填充", ResizeOptions{Mode: ResizeLetterbox, Width: 400, Height: 100, Pad: gray}, 400, 100,
+			YoloBox{X1: 120, Y1: 10, X2: 200, Y2: 90}, YoloBox{X1: 100, X2: 300, Y2: 100}, image.Pt(50, 50), true, false},
+	}
+	for _, tt := range tests {
+		out, xf := ApplyResize(src, tt.opt)
+		if b := out.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h || xf.W != tt.w || xf.H != tt.h {
+			t.Errorf("%s: 尺寸 %dx%d (变换 %dx%d), want %dx%d", tt.name, b.Dx(), b.Dy(), xf.W, xf.H, tt.w, tt.h)
+			continue
+		}
+		if tt.samePtr && out != image.Image(src) {
+			t.Errorf("%s: 无需调整时应返回原图", tt.name)
+		}
+		got := xf.Boxes([]YoloBox{box, tri})
+		for i, want := range []YoloBox{tt.box, tt.tri} {
+			g := got[i]
+			if g.X1 != want.X1 || g.Y1 != want.Y1 || g.X2 != want.X2 || g.Y2 != want.Y2 {
+				t.Errorf("%s: 框 %d = (%v,%v,%v,%v), want (%v,%v,%v,%v)", tt.name, i, g.X1, g.Y1, g.X2, g.Y2, want.X1, want.Y1, want.X2, want.Y2)
+			}
+		}
+		if len(got[1].Poly) != 3 {
+			t.Errorf("%s: 多边形顶点 %d 个，want 3", tt.name, len(got[1].Poly))
+		}
+		if tt.padded {
+			if c := color.RGBAModel.Convert(out.At(tt.padAt.X, tt.padAt.Y)); c != gray {
+				t.Errorf("%s: (%v) 处颜色 %v, want 填充色 %v", tt.name, tt.padAt, c, gray)
+			}
+			// 图片区域中心仍为原图颜色
+			cx, cy := int(xf.OffX)+int(float64(200)*xf.ScaleX)/2, int(xf.OffY)+int(float64(100)*xf.ScaleY)/2
+			if c := color.RGBAModel.Convert(out.At(cx, cy)); c != red {
+				t.Errorf("%s: 图片中心颜色 %v, want %v", tt.name, c, red)
+			}
+		}
+	}
+}
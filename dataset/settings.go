@@ -0,0 +1,260 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ==================== 构建设置 (界面 / 命令行 / 配置文件共用) ====================
+
+// Settings 构建设置的原始输入，字段与界面控件一一对应；
+// 划分比例、种子、尺寸、颜色等保持文本形式，由 Options 统一解析和校验
+type Settings struct {
+	Sources      []string `json:"sources" yaml:"sources"`
+	Output       string   `json:"output" yaml:"output"`
+	Classes      string   `json:"classes" yaml:"classes"` // 逗号分隔
+	RelativeYaml bool     `json:"relative_yaml" yaml:"relative_yaml"`
+	YamlExtra    string   `json:"yaml_extra" yaml:"yaml_extra"`
+
+	Train       string `json:"train" yaml:"train"` // 比例 0.8、百分比 80% 或张数 200张 / n=200
+	Val         string `json:"val" yaml:"val"`
+	Test        string `json:"test" yaml:"test"` // 留空表示剩余全部
+	Seed        string `json:"seed" yaml:"seed"` // 留空时随机生成
+	Stratify    bool   `json:"stratify" yaml:"stratify"`
+	Folds       int    `json:"folds" yaml:"folds"`
+	Incremental bool   `json:"incremental" yaml:"incremental"`
+	Group       string `json:"group" yaml:"group"` // none / folder / regex / flag
+	GroupRegex  string `json:"group_regex" yaml:"group_regex"`
+
+	Process    bool   `json:"process" yaml:"process"`
+	MaxKB      int    `json:"max_kb" yaml:"max_kb"`
+	Verbose    bool   `json:"verbose" yaml:"verbose"` // 逐张记录压缩结果
+	Resize     string `json:"resize" yaml:"resize"`   // none / max / exact / letterbox
+	ResizeSize string `json:"resize_size" yaml:"resize_size"`
+	Pad        string `json:"pad" yaml:"pad"`
+	Alpha      string `json:"alpha" yaml:"alpha"` // composite / png
+	AlphaBg    string `json:"alpha_bg" yaml:"alpha_bg"`
+	TiffPages  string `json:"tiff_pages" yaml:"tiff_pages"` // first / all
+
+	Tile        bool    `json:"tile" yaml:"tile"`
+	TileSize    int     `json:"tile_size" yaml:"tile_size"`
+	TileOverlap int     `json:"tile_overlap" yaml:"tile_overlap"`
+	TileVisible float64 `json:"tile_visible" yaml:"tile_visible"`
+
+	Augment   bool    `json:"augment" yaml:"augment"`
+	AugCopies int     `json:"aug_copies" yaml:"aug_copies"`
+	AugHFlip  bool    `json:"aug_hflip" yaml:"aug_hflip"`
+	AugVFlip  bool    `json:"aug_vflip" yaml:"aug_vflip"`
+	AugRot90  bool    `json:"aug_rot90" yaml:"aug_rot90"`
+	AugJitter float64 `json:"aug_jitter" yaml:"aug_jitter"`
+	AugCrop   float64 `json:"aug_crop" yaml:"aug_crop"`
+
+	Ignore       bool   `json:"ignore" yaml:"ignore"`
+	IgnoreLabels string `json:"ignore_labels" yaml:"ignore_labels"`
+	IgnoreFill   string `json:"ignore_fill" yaml:"ignore_fill"`
+	Redact       bool   `json:"redact" yaml:"redact"`
+	RedactLabels string `json:"redact_labels" yaml:"redact_labels"`
+	RedactMode   string `json:"redact_mode" yaml:"redact_mode"` // blur / pixelate / fill
+	RedactDrop   bool   `json:"redact_drop" yaml:"redact_drop"`
+	RedactFill   string `json:"redact_fill" yaml:"redact_fill"` // fill 方式的填充色
+
+	OutputMode string `json:"output_mode" yaml:"output_mode"` // copy / hardlink / symlink / list
+}
+
+// DefaultSettings 默认设置，与界面初始值一致
+func DefaultSettings() Settings {
+	return Settings{
+		Train: "0.8", Val: "0.2",
+		Group:   GroupModeKeys[GroupNone],
+		Process: true, MaxKB: 500,
+		Resize: ResizeModeKeys[ResizeNone], ResizeSize: "640", Pad: "#727272",
+		Alpha: AlphaModeKeys[AlphaComposite], AlphaBg: "#FFFFFF",
+		TiffPages: TiffPageModeKeys[TiffFirstPage],
+		TileSize:  1280, TileOverlap: 128, TileVisible: 0.3,
+		AugCopies: 2, AugHFlip: true, AugJitter: 0.2, AugCrop: 0.8,
+		IgnoreFill: "#727272",
+		RedactMode: RedactModeKeys[RedactBlur], RedactFill: "#000000",
+		OutputMode: OutputModeKeys[OutputCopy],
+	}
+}
+
+// modeIndex 按键名查找模式下标，留空取第一个
+func modeIndex(keys []string, key, what string) (int, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return 0, nil
+	}
+	for i, k := range keys {
+		if strings.EqualFold(k, key) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s无效: %q (可选 %s)", what, key, strings.Join(keys, " / "))
+}
+
+// Options 解析并校验设置；种子留空时随机生成并写回 s.Seed
+func (s *Settings) Options() (Options, error) {
+	var o Options
+	if len(s.Sources) == 0 {
+		return o, fmt.Errorf("未添加数据源")
+	}
+	if s.Output == "" {
+		return o, fmt.Errorf("未选择输出目录")
+	}
+	if strings.TrimSpace(s.Classes) == "" {
+		return o, fmt.Errorf("未填写类别")
+	}
+	o.Sources, o.Output = s.Sources, s.Output
+	o.Process, o.MaxKB, o.Stratify, o.Folds, o.Incremental = s.Process, s.MaxKB, s.Stratify, s.Folds, s.Incremental
+	o.RelativeYaml, o.YamlExtra, o.Verbose = s.RelativeYaml, s.YamlExtra, s.Verbose
+
+	var err error
+	if o.Classes, err = ParseClassList(s.Classes); err != nil {
+		return o, err
+	}
+	if o.Split, err = ParseSplitSpec(s.Train, s.Val, s.Test); err != nil {
+		return o, fmt.Errorf("划分设置无效: %v", err)
+	}
+	if strings.TrimSpace(s.Seed) == "" {
+		s.Seed = strconv.FormatInt(time.Now().UnixNano()%1000000000, 10)
+	}
+	if o.Seed, err = strconv.ParseInt(strings.TrimSpace(s.Seed), 10, 64); err != nil {
+		return o, fmt.Errorf("种子必须是整数")
+	}
+
+	group, err := modeIndex(GroupModeKeys, s.Group, "分组方式")
+	if err != nil {
+		return o, err
+	}
+	o.Group = GroupMode(group)
+	if o.Group == GroupRegex {
+		if s.GroupRegex == "" {
+			return o, fmt.Errorf("未填写分组正则")
+		}
+		if o.GroupRe, err = regexp.Compile(s.GroupRegex); err != nil {
+			return o, fmt.Errorf("分组正则无效: %v", err)
+		}
+	}
+	mode, err := modeIndex(OutputModeKeys, s.OutputMode, "输出方式")
+	if err != nil {
+		return o, err
+	}
+	o.OutputMode = OutputMode(mode)
+
+	if s.Tile {
+		o.Tile = TileOptions{Size: s.TileSize, Overlap: s.TileOverlap, MinVisible: s.TileVisible}
+	}
+	if s.Augment {
+		o.Augment = AugmentOptions{Copies: s.AugCopies, HFlip: s.AugHFlip, VFlip: s.AugVFlip, Rot90: s.AugRot90, Jitter: s.AugJitter, Crop: s.AugCrop}
+	}
+
+	resize, err := modeIndex(ResizeModeKeys, s.Resize, "尺寸调整方式")
+	if err != nil {
+		return o, err
+	}
+	o.Resize.Mode = ResizeMode(resize)
+	if o.Resize.Mode != ResizeNone {
+		if o.Resize.Width, o.Resize.Height, err = ParseSize(s.ResizeSize); err != nil {
+			return o, err
+		}
+		if o.Resize.Pad, err = ParseHexColor(s.Pad); err != nil {
+			return o, fmt.Errorf("填充%v", err)
+		}
+	}
+	alpha, err := modeIndex(AlphaModeKeys, s.Alpha, "透明处理方式")
+	if err != nil {
+		return o, err
+	}
+	o.Alpha.Mode = AlphaMode(alpha)
+	if o.Alpha.Mode == AlphaComposite {
+		if o.Alpha.Background, err = ParseHexColor(s.AlphaBg); err != nil {
+			return o, fmt.Errorf("透明底色%v", err)
+		}
+	}
+	tiff, err := modeIndex(TiffPageModeKeys, s.TiffPages, "TIFF 分页方式")
+	if err != nil {
+		return o, err
+	}
+	o.TiffPages = TiffPageMode(tiff)
+
+	if s.Ignore {
+		if o.Ignore.Labels, err = ParseClassList(s.IgnoreLabels); err != nil {
+			return o, fmt.Errorf("忽略标签无效: %v", err)
+		}
+		if o.Ignore.Fill, err = ParseHexColor(s.IgnoreFill); err != nil {
+			return o, fmt.Errorf("忽略区域填充%v", err)
+		}
+	}
+	if s.Redact {
+		if o.Redact.Labels, err = ParseClassList(s.RedactLabels); err != nil {
+			return o, fmt.Errorf("脱敏标签无效: %v", err)
+		}
+		redact, err := modeIndex(RedactModeKeys, s.RedactMode, "脱敏方式")
+		if err != nil {
+			return o, err
+		}
+		o.Redact.Mode, o.Redact.Drop = RedactMode(redact), s.RedactDrop
+		if o.Redact.Fill, err = ParseHexColor(s.RedactFill); err != nil {
+			return o, fmt.Errorf("脱敏填充%v", err)
+		}
+	}
+	return o, o.Validate()
+}
+
+// LoadSettingsFile 读取 JSON 或 YAML 项目文件 (按扩展名判断，.json 以外按 YAML 解析)，
+// 文件中没有的键保持默认值；相对路径的数据源与输出以项目文件所在目录为基准
+func LoadSettingsFile(path string) (Settings, error) {
+	s := DefaultSettings()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if isJSONPath(path) {
+		err = json.Unmarshal(raw, &s)
+	} else {
+		err = yaml.Unmarshal(raw, &s)
+	}
+	if err != nil {
+		return s, fmt.Errorf("%s 格式错误: %v", filepath.Base(path), err)
+	}
+	base := filepath.Dir(path)
+	for i, src := range s.Sources {
+		s.Sources[i] = resolvePath(base, src)
+	}
+	s.Output = resolvePath(base, s.Output)
+	return s, nil
+}
+
+// SaveSettingsFile 把设置写成项目文件，格式同 LoadSettingsFile
+func SaveSettingsFile(path string, s Settings) error {
+	var data []byte
+	var err error
+	if isJSONPath(path) {
+		data, err = json.MarshalIndent(s, "", "  ")
+	} else {
+		data, err = yaml.Marshal(s)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func isJSONPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+func resolvePath(base, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
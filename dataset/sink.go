@@ -0,0 +1,191 @@
+package dataset
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==================== 输出目标 (文件夹 / 压缩包) ====================
+
+// DatasetSink 数据集输出目标，rel 一律使用 / 分隔的相对路径
+type DatasetSink interface {
+	WriteFile(rel string, data []byte) error
+	PlaceImage(src, rel string, mode OutputMode) (OutputMode, error)
+	Close() error
+}
+
+// IsArchivePath 输出路径是否指向压缩包
+func IsArchivePath(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// NewDatasetSink 按输出路径创建文件夹或压缩包输出目标
+func NewDatasetSink(out string) (DatasetSink, error) {
+	if IsArchivePath(out) {
+		return NewArchiveSink(out)
+	}
+	return &dirSink{root: out}, nil
+}
+
+// trackedSink 记录本次运行写入过的路径，取消时据此清理未完成的输出
+type trackedSink struct {
+	DatasetSink
+	out   string
+	mu    sync.Mutex
+	paths []string
+}
+
+// track 在写入前登记，写到一半失败的文件也能被清理；p 为输出内的相对路径或绝对路径
+func (t *trackedSink) track(p string) {
+	t.mu.Lock()
+	t.paths = append(t.paths, p)
+	t.mu.Unlock()
+}
+
+func (t *trackedSink) WriteFile(rel string, data []byte) error {
+	t.track(rel)
+	return t.DatasetSink.WriteFile(rel, data)
+}
+
+func (t *trackedSink) PlaceImage(src, rel string, mode OutputMode) (OutputMode, error) {
+	t.track(rel)
+	return t.DatasetSink.PlaceImage(src, rel, mode)
+}
+
+// Discard 关闭输出并删除本次写入的文件；压缩包整个删除
+func (t *trackedSink) Discard() {
+	t.DatasetSink.Close()
+	if IsArchivePath(t.out) {
+		os.Remove(t.out)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, p := range t.paths {
+		os.Remove(OutputPath(t.out, p))
+	}
+}
+
+// dirSink 写入普通文件夹
+type dirSink struct {
+	root string
+}
+
+func (d *dirSink) WriteFile(rel string, data []byte) error {
+	dst := filepath.Join(d.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	os.Remove(dst) // 同 PlaceFile，避免写穿上次留下的链接
+	return os.WriteFile(dst, data, 0644)
+}
+
+func (d *dirSink) PlaceImage(src, rel string, mode OutputMode) (OutputMode, error) {
+	dst := filepath.Join(d.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return mode, err
+	}
+	return PlaceFile(src, dst, mode)
+}
+
+func (d *dirSink) Close() error { return nil }
+
+// archiveSink 直接流式写入 .zip 或 .tar.gz，多个 worker 共享时按条目加锁
+type archiveSink struct {
+	mu   sync.Mutex
+	f    *os.File
+	zw   *zip.Writer
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	seen map[string]bool
+}
+
+// NewArchiveSink 创建压缩包输出目标
+func NewArchiveSink(path string) (*archiveSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &archiveSink{f: f, seen: make(map[string]bool)}
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		a.zw = zip.NewWriter(f)
+	} else {
+		a.gz = gzip.NewWriter(f)
+		a.tw = tar.NewWriter(a.gz)
+	}
+	return a, nil
+}
+
+// add 写入一个条目；JPEG 等已压缩的图片在 zip 中以 Store 方式保存
+func (a *archiveSink) add(rel string, size int64, r io.Reader) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.seen[rel] {
+		return fmt.Errorf("压缩包内文件重复: %s", rel)
+	}
+	a.seen[rel] = true
+	if a.zw != nil {
+		method := zip.Deflate
+		if strings.HasPrefix(rel, "images/") {
+			method = zip.Store
+		}
+		w, err := a.zw.CreateHeader(&zip.FileHeader{Name: rel, Method: method, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	}
+	if err := a.tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tw, r)
+	return err
+}
+
+func (a *archiveSink) WriteFile(rel string, data []byte) error {
+	return a.add(rel, int64(len(data)), bytes.NewReader(data))
+}
+
+// PlaceImage 压缩包内无法链接，始终复制原始字节
+func (a *archiveSink) PlaceImage(src, rel string, mode OutputMode) (OutputMode, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return OutputCopy, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return OutputCopy, err
+	}
+	return OutputCopy, a.add(rel, st.Size(), f)
+}
+
+func (a *archiveSink) Close() error {
+	var err error
+	if a.zw != nil {
+		err = a.zw.Close()
+	} else {
+		err = a.tw.Close()
+		if gzErr := a.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if fErr := a.f.Close(); err == nil {
+		err = fErr
+	}
+	return err
+}
@@ -0,0 +1,554 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ==================== 数据集划分 ====================
+
+// FilePair 一张图片及其同名 LabelMe JSON
+type FilePair struct{ ImgPath, JsonPath string }
+
+// ShuffleTasks 先按路径排序再用种子打乱，保证同一种子、同一批输入得到相同顺序，
+// 与目录读取顺序和数据源添加顺序无关
+func ShuffleTasks(tasks []FilePair, seed int64) {
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ImgPath < tasks[j].ImgPath })
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(tasks), func(i, j int) { tasks[i], tasks[j] = tasks[j], tasks[i] })
+}
+
+// SplitNames 划分名称，下标与比例数组一致
+var SplitNames = []string{"train", "val", "test"}
+
+// SplitSize 一个划分的大小：比例、绝对数量，或留空取剩余
+type SplitSize struct {
+	Ratio   float64
+	Count   int
+	IsCount bool
+	Rest    bool
+}
+
+// ParseSplitSize 解析单个输入框："0.8"、"1" 或 "80%" 为比例，"200张" 或 "n=200" 为张数，留空为剩余。
+// 张数必须显式标出，否则 "1" 是全部还是 1 张无法区分
+func ParseSplitSize(text string) (SplitSize, error) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		return SplitSize{Rest: true}, nil
+	case strings.HasSuffix(text, "%"):
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(text, "%")), 64)
+		if err != nil || v < 0 || v > 100 {
+			return SplitSize{}, fmt.Errorf("%q 不是 0%%~100%% 之间的百分比", text)
+		}
+		return SplitSize{Ratio: v / 100}, nil
+	case strings.HasSuffix(text, "张") || strings.HasPrefix(strings.ToLower(text), "n="):
+		num := strings.TrimSpace(strings.TrimSuffix(text, "张"))
+		if strings.HasPrefix(strings.ToLower(num), "n=") {
+			num = strings.TrimSpace(num[2:])
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 0 {
+			return SplitSize{}, fmt.Errorf("%q 不是有效的张数", text)
+		}
+		if n == 0 {
+			return SplitSize{}, nil
+		}
+		return SplitSize{Count: n, IsCount: true}, nil
+	default:
+		v, err := strconv.ParseFloat(text, 64)
+		if err == nil && v > 1 && v == math.Trunc(v) {
+			return SplitSize{}, fmt.Errorf("%q 不是 0~1 之间的比例；张数请写成 \"%s张\" 或 \"n=%s\"", text, text, text)
+		}
+		if err != nil || v < 0 || v > 1 {
+			return SplitSize{}, fmt.Errorf("%q 不是 0~1 之间的比例", text)
+		}
+		return SplitSize{Ratio: v}, nil
+	}
+}
+
+// SplitSpec train/val/test 三个划分的大小
+type SplitSpec [3]SplitSize
+
+// ParseSplitSpec 解析并校验三个输入框：最多留空一项；不留空时比例之和必须为 1 且不能用张数
+func ParseSplitSpec(train, val, test string) (SplitSpec, error) {
+	var spec SplitSpec
+	rest, counts := 0, 0
+	sum := 0.0
+	for i, text := range []string{train, val, test} {
+		sz, err := ParseSplitSize(text)
+		if err != nil {
+			return spec, fmt.Errorf("%s: %v", SplitNames[i], err)
+		}
+		spec[i] = sz
+		switch {
+		case sz.Rest:
+			rest++
+		case sz.IsCount:
+			counts++
+		default:
+			sum += sz.Ratio
+		}
+	}
+	switch {
+	case rest > 1:
+		return spec, fmt.Errorf("最多只能留空一项 (取剩余)")
+	case sum > 1+1e-9:
+		return spec, fmt.Errorf("比例之和为 %.4g，超过 1", sum)
+	case rest == 0 && counts > 0:
+		return spec, fmt.Errorf("使用张数时需留空一项取剩余")
+	case rest == 0 && math.Abs(sum-1) > 1e-6:
+		return spec, fmt.Errorf("比例之和为 %.4g，应为 1 (或留空一项取剩余)", sum)
+	}
+	return spec, nil
+}
+
+// Resolve 按图片总数换算出三个划分的张数
+func (spec SplitSpec) Resolve(n int) ([]int, error) {
+	counts := make([]int, 3)
+	var ratioIdx []int
+	var weights []float64
+	used := 0
+	for i, sz := range spec {
+		if sz.IsCount {
+			counts[i] = sz.Count
+			used += sz.Count
+		} else if !sz.Rest {
+			ratioIdx = append(ratioIdx, i)
+			weights = append(weights, sz.Ratio*float64(n))
+		}
+	}
+	if used > n {
+		return nil, fmt.Errorf("指定张数之和 %d 超过图片总数 %d", used, n)
+	}
+	restIdx := -1
+	for i, sz := range spec {
+		if sz.Rest {
+			restIdx = i
+		}
+	}
+	// 有剩余项时比例部分四舍五入，否则向下取整后按最大余数法凑满
+	for k, i := range ratioIdx {
+		if restIdx >= 0 {
+			counts[i] = int(math.Round(weights[k]))
+		} else {
+			counts[i] = int(math.Floor(weights[k] + 1e-9))
+		}
+		used += counts[i]
+	}
+	if restIdx >= 0 {
+		// 四舍五入可能多出几张，从比例项中扣回
+		for k := 0; used > n && len(ratioIdx) > 0; k++ {
+			if i := ratioIdx[k%len(ratioIdx)]; counts[i] > 0 {
+				counts[i]--
+				used--
+			}
+		}
+		counts[restIdx] = n - used
+		return counts, nil
+	}
+	order := make([]int, len(ratioIdx))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		fa := weights[order[a]] - math.Floor(weights[order[a]])
+		fb := weights[order[b]] - math.Floor(weights[order[b]])
+		return fa > fb
+	})
+	for k := 0; used < n && len(order) > 0; k++ {
+		counts[ratioIdx[order[k%len(order)]]]++
+		used++
+	}
+	return counts, nil
+}
+
+// DescribeCounts 生成 "train 800 / val 200 / test 0" 形式的说明
+func DescribeCounts(names []string, counts []int) string {
+	var parts []string
+	for i, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d", name, counts[i]))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// RemainingCounts 增量模式下新图片的目标张数：总目标减去沿用的张数，再按比例凑到 n 张
+func RemainingCounts(target, kept []int, n int) []int {
+	want := make([]float64, len(target))
+	total := 0.0
+	for i := range target {
+		want[i] = math.Max(0, float64(target[i]-kept[i]))
+		total += want[i]
+	}
+	counts := make([]int, len(target))
+	if total == 0 {
+		for i := range target {
+			want[i] = float64(target[i])
+			total += want[i]
+		}
+	}
+	if total == 0 {
+		return counts
+	}
+	used := 0
+	fracs := make([]int, len(target))
+	for i := range want {
+		v := want[i] * float64(n) / total
+		counts[i] = int(math.Floor(v))
+		used += counts[i]
+		fracs[i] = i
+	}
+	sort.SliceStable(fracs, func(a, b int) bool {
+		va := want[fracs[a]] * float64(n) / total
+		vb := want[fracs[b]] * float64(n) / total
+		return va-math.Floor(va) > vb-math.Floor(vb)
+	})
+	for k := 0; used < n; k++ {
+		counts[fracs[k%len(fracs)]]++
+		used++
+	}
+	return counts
+}
+
+// CountsToRatios 张数换算为比例，供分层/分组划分使用
+func CountsToRatios(counts []int) []float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	ratios := make([]float64, len(counts))
+	if total == 0 {
+		return ratios
+	}
+	for i, c := range counts {
+		ratios[i] = float64(c) / float64(total)
+	}
+	return ratios
+}
+
+// SplitByCounts 按顺序切分：前 counts[0] 张为第 0 个划分，依此类推
+func SplitByCounts(counts []int) []int {
+	var subsets []int
+	for j, c := range counts {
+		for k := 0; k < c; k++ {
+			subsets = append(subsets, j)
+		}
+	}
+	return subsets
+}
+
+// EqualCounts n 张图片尽量均分为 k 份，用于 K 折
+func EqualCounts(n, k int) []int {
+	counts := make([]int, k)
+	for i := range counts {
+		counts[i] = n / k
+		if i < n%k {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// FoldNames K 折的划分名 fold_0 ... fold_{k-1}
+func FoldNames(k int) []string {
+	names := make([]string, k)
+	for i := range names {
+		names[i] = fmt.Sprintf("fold_%d", i)
+	}
+	return names
+}
+
+// StratifiedSplit 多标签迭代分层 (Sechidis 等, 2011)：
+// 每轮取剩余样本最少的类别，把含该类的图片逐张分给对该类缺口最大的划分，
+// 使每个类别在各划分中的占比接近给定比例。classes[i] 为第 i 张图片包含的类别 ID，
+// 返回每张图片所属划分在 ratios 中的下标
+func StratifiedSplit(classes [][]int, ratios []float64, seed int64) []int {
+	want := make([]float64, len(ratios))
+	for j := range ratios {
+		want[j] = ratios[j] * float64(len(classes))
+	}
+	return stratifiedSplit(classes, want, seed)
+}
+
+// StratifiedSplitCounts 同 StratifiedSplit，但各划分的图片数严格等于 counts (总和应为图片数)，
+// 用于按张数划分
+func StratifiedSplitCounts(classes [][]int, counts []int, seed int64) []int {
+	want := make([]float64, len(counts))
+	for j, c := range counts {
+		want[j] = float64(c)
+	}
+	return stratifiedSplit(classes, want, seed)
+}
+
+// stratifiedSplit 分层划分的实现，want[j] 为第 j 个划分的目标图片数；
+// 已分满的划分不再接收图片，目标为整数且总和等于图片数时各划分张数与目标一致
+func stratifiedSplit(classes [][]int, want []float64, seed int64) []int {
+	n := len(classes)
+	r := rand.New(rand.NewSource(seed))
+	ratios := make([]float64, len(want))
+	for j := range want {
+		if n > 0 {
+			ratios[j] = want[j] / float64(n)
+		}
+	}
+	want = append([]float64(nil), want...)
+
+	// 每个类别的需求
+	wantCls := make(map[int][]float64)
+	remaining := make(map[int]int)
+	for _, cs := range classes {
+		for _, c := range uniqueInts(cs) {
+			remaining[c]++
+		}
+	}
+	for c, cnt := range remaining {
+		w := make([]float64, len(ratios))
+		for j := range ratios {
+			w[j] = ratios[j] * float64(cnt)
+		}
+		wantCls[c] = w
+	}
+
+	subsets := make([]int, n)
+	assigned := make([]bool, n)
+	assign := func(i, j int) {
+		assigned[i] = true
+		subsets[i] = j
+		want[j]--
+		for _, c := range uniqueInts(classes[i]) {
+			wantCls[c][j]--
+			remaining[c]--
+		}
+	}
+	// 在候选划分 (未分满的，都满了则为目标不为 0 的) 中按 主键 -> 总需求 -> 随机 选出一个
+	pick := func(primary func(j int) float64) int {
+		open := false
+		for j := range want {
+			open = open || want[j] > 0
+		}
+		best := []int{}
+		for j := range ratios {
+			if ratios[j] <= 0 || (open && want[j] <= 0) {
+				continue
+			}
+			if len(best) == 0 {
+				best = []int{j}
+				continue
+			}
+			b := best[0]
+			switch {
+			case primary(j) > primary(b) || (primary(j) == primary(b) && want[j] > want[b]):
+				best = []int{j}
+			case primary(j) == primary(b) && want[j] == want[b]:
+				best = append(best, j)
+			}
+		}
+		if len(best) == 0 {
+			return 0
+		}
+		return best[r.Intn(len(best))]
+	}
+
+	for {
+		// 剩余样本最少的类别 (同数量取 ID 小的，保证确定性)
+		label, minCnt := -1, 0
+		for c, cnt := range remaining {
+			if cnt > 0 && (label < 0 || cnt < minCnt || (cnt == minCnt && c < label)) {
+				label, minCnt = c, cnt
+			}
+		}
+		if label < 0 {
+			break
+		}
+		for i := 0; i < n; i++ {
+			if assigned[i] || !containsInt(classes[i], label) {
+				continue
+			}
+			w := wantCls[label]
+			assign(i, pick(func(j int) float64 { return w[j] }))
+		}
+	}
+	// 无标注的图片按总需求分配
+	for i := 0; i < n; i++ {
+		if !assigned[i] {
+			assign(i, pick(func(j int) float64 { return want[j] }))
+		}
+	}
+	return subsets
+}
+
+// GroupMode 分组方式：同组图片 (同一相机/会话的连续帧) 整组进入同一个划分，避免泄漏
+type GroupMode int
+
+const (
+	GroupNone   GroupMode = iota // 不分组
+	GroupFolder                  // 按源文件夹
+	GroupRegex                   // 按文件名正则的第一个捕获组
+	GroupFlag                    // 按 LabelMe flags 中为 true 的项
+)
+
+var GroupModeNames = []string{"不分组", "按源文件夹", "按文件名正则", "按 LabelMe flag"}
+
+// 分组方式的英文键名，用于配置文件与命令行
+var GroupModeKeys = []string{"none", "folder", "regex", "flag"}
+
+// GroupKeys 计算每张图片的分组键；取不到键的图片单独成组
+func GroupKeys(tasks []FilePair, mode GroupMode, re *regexp.Regexp) []string {
+	keys := make([]string, len(tasks))
+	for i, t := range tasks {
+		key := ""
+		switch mode {
+		case GroupFolder:
+			key = filepath.Dir(t.ImgPath)
+		case GroupRegex:
+			if m := re.FindStringSubmatch(filepath.Base(t.ImgPath)); m != nil {
+				key = m[0]
+				if len(m) > 1 {
+					key = m[1]
+				}
+			}
+		case GroupFlag:
+			key = strings.Join(readLabelMeFlags(t.JsonPath), "+")
+		}
+		if key == "" {
+			key = "\x00" + t.ImgPath // 与正常键区分
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// readLabelMeFlags 读取 LabelMe JSON 中值为 true 的 flag 名 (已排序)
+func readLabelMeFlags(jsonPath string) []string {
+	raw, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil
+	}
+	var data struct {
+		Flags map[string]bool `json:"flags"`
+	}
+	if json.Unmarshal(raw, &data) != nil {
+		return nil
+	}
+	var names []string
+	for k, v := range data.Flags {
+		if v {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupSplit 整组分配：按组大小从大到小，依次放入离目标图片数差距最大的划分
+func GroupSplit(keys []string, ratios []float64) []int {
+	members := make(map[string][]int)
+	var order []string // 按首次出现顺序，输入已按种子打乱
+	for i, k := range keys {
+		if _, ok := members[k]; !ok {
+			order = append(order, k)
+		}
+		members[k] = append(members[k], i)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(members[order[a]]) > len(members[order[b]]) })
+
+	have := make([]float64, len(ratios))
+	subsets := make([]int, len(keys))
+	for _, k := range order {
+		best := -1
+		for j := range ratios {
+			if ratios[j] <= 0 {
+				continue
+			}
+			if best < 0 || ratios[j]*float64(len(keys))-have[j] > ratios[best]*float64(len(keys))-have[best] {
+				best = j
+			}
+		}
+		if best < 0 {
+			best = 0
+		}
+		for _, i := range members[k] {
+			subsets[i] = best
+		}
+		have[best] += float64(len(members[k]))
+	}
+	return subsets
+}
+
+// GroupStratifiedSplit 以组为单位做分层划分，组的类别取组内所有图片的并集
+func GroupStratifiedSplit(keys []string, classes [][]int, ratios []float64, seed int64) []int {
+	index := make(map[string]int)
+	var groupClasses [][]int
+	for i, k := range keys {
+		g, ok := index[k]
+		if !ok {
+			g = len(groupClasses)
+			index[k] = g
+			groupClasses = append(groupClasses, nil)
+		}
+		groupClasses[g] = append(groupClasses[g], classes[i]...)
+	}
+	groupSubsets := StratifiedSplit(groupClasses, ratios, seed)
+	subsets := make([]int, len(keys))
+	for i, k := range keys {
+		subsets[i] = groupSubsets[index[k]]
+	}
+	return subsets
+}
+
+// SplitClassTable 统计每个类别在各划分中出现的图片数，返回可直接打印的文本行
+func SplitClassTable(classes [][]int, subsets, splitNames, names []string) []string {
+	counts := make([]map[string]int, len(names))
+	for i := range counts {
+		counts[i] = make(map[string]int)
+	}
+	for i, cs := range classes {
+		for _, c := range uniqueInts(cs) {
+			if c >= 0 && c < len(names) {
+				counts[c][subsets[i]]++
+			}
+		}
+	}
+	header := fmt.Sprintf("%-16s", "类别")
+	for _, sub := range splitNames {
+		header += fmt.Sprintf(" %8s", sub)
+	}
+	lines := []string{header}
+	for c, name := range names {
+		line := fmt.Sprintf("%-16s", name)
+		for _, sub := range splitNames {
+			line += fmt.Sprintf(" %8d", counts[c][sub])
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func uniqueInts(xs []int) []int {
+	seen := make(map[int]bool, len(xs))
+	var out []int
+	for _, x := range xs {
+		if !seen[x] {
+			seen[x] = true
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func containsInt(xs []int, v int) bool {
+	for _, x := range xs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
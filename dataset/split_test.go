@@ -0,0 +1,165 @@
+package dataset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSplitSpec(t *testing.T) {
+	tests := []struct {
+		train, val, test string
+		err              string // 为空表示应当成功
+	}{
+		{"0.8", "0.1", "0.1", ""},
+		{"80%", "20%", "0", ""},
+		{"200张", "", "0.1", ""},
+		{"n=200", "", "0", ""},
+		{"1", "0", "", ""}, // 不带单位的 1 是比例
+		{"200", "", "0", "200张"},
+		{"n=abc", "", "0", "train"},
+		{"1.5张", "", "0", "train"},
+		{"0.8", "", "", "最多只能留空一项"},
+		{"0.8", "0.3", "", "超过 1"},
+		{"200张", "0.1", "0.1", "需留空一项"},
+		{"0.5", "0.3", "0.1", "应为 1"},
+		{"abc", "", "0", "train"},
+		{"0.8", "120%", "", "val"},
+		{"0.8", "", "1.5", "test"},
+		{"-3", "", "0", "train"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSplitSpec(tt.train, tt.val, tt.test)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("ParseSplitSpec(%q, %q, %q): %v", tt.train, tt.val, tt.test, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("ParseSplitSpec(%q, %q, %q) = %v, want error containing %q", tt.train, tt.val, tt.test, err, tt.err)
+		}
+	}
+}
+
+func TestSplitSpecResolve(t *testing.T) {
+	tests := []struct {
+		train, val, test string
+		n                int
+		want             []int // 为 nil 表示应当报错
+	}{
+		{"0.8", "0.2", "0", 10, []int{8, 2, 0}},
+		{"80%", "20%", "", 10, []int{8, 2, 0}},
+		{"0.7", "0.2", "0.1", 15, []int{11, 3, 1}}, // 最大余数法，余数相同时先给前面的划分
+		{"200张", "", "0.1", 1000, []int{200, 700, 100}},
+		{"1", "0", "", 7, []int{7, 0, 0}},
+		{"0.5", "0.5", "", 3, []int{1, 2, 0}}, // 四舍五入多出的张数从比例项中扣回
+		{"0.8", "0.1", "0.1", 0, []int{0, 0, 0}},
+		{"n=20", "", "0", 10, nil},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSplitSpec(tt.train, tt.val, tt.test)
+		if err != nil {
+			t.Fatalf("ParseSplitSpec(%q, %q, %q): %v", tt.train, tt.val, tt.test, err)
+		}
+		got, err := spec.Resolve(tt.n)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Resolve(%q, %q, %q; n=%d) = %v, want error", tt.train, tt.val, tt.test, tt.n, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q, %q, %q; n=%d) = %v, %v, want %v", tt.train, tt.val, tt.test, tt.n, got, err, tt.want)
+		}
+	}
+}
+
+// repeatClasses 生成 n 张只含类别 c 的图片
+func repeatClasses(n int, c ...int) [][]int {
+	out := make([][]int, n)
+	for i := range out {
+		out[i] = c
+	}
+	return out
+}
+
+func TestStratifiedSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes [][]int
+		ratios  []float64
+	}{
+		{"两个类别各半", append(repeatClasses(50, 0), repeatClasses(50, 1)...), []float64{0.8, 0.2, 0}},
+		{"少数类", append(repeatClasses(90, 0), repeatClasses(10, 1)...), []float64{0.8, 0.1, 0.1}},
+		{"多标签与无标注", append(append(repeatClasses(40, 0, 1), repeatClasses(40, 1)...), repeatClasses(20)...), []float64{0.5, 0.5, 0}},
+	}
+	for _, tt := range tests {
+		got := StratifiedSplit(tt.classes, tt.ratios, 1)
+		if len(got) != len(tt.classes) {
+			t.Fatalf("%s: len = %d, want %d", tt.name, len(got), len(tt.classes))
+		}
+		if again := StratifiedSplit(tt.classes, tt.ratios, 1); !reflect.DeepEqual(got, again) {
+			t.Errorf("%s: 同一种子结果不同", tt.name)
+		}
+		// 每个类别在各划分中的图片数与比例相差不超过 1 张，比例为 0 的划分不分配
+		total := make(map[int]int)
+		per := make(map[int][]int)
+		for i, cs := range tt.classes {
+			if tt.ratios[got[i]] == 0 {
+				t.Errorf("%s: 图片 %d 分到了比例为 0 的划分 %d", tt.name, i, got[i])
+			}
+			for _, c := range cs {
+				if per[c] == nil {
+					per[c] = make([]int, len(tt.ratios))
+				}
+				per[c][got[i]]++
+				total[c]++
+			}
+		}
+		for c, counts := range per {
+			for j, r := range tt.ratios {
+				if want := r * float64(total[c]); float64(counts[j]) < want-1 || float64(counts[j]) > want+1 {
+					t.Errorf("%s: 类别 %d 在划分 %d 中有 %d 张，期望约 %.1f", tt.name, c, j, counts[j], want)
+				}
+			}
+		}
+	}
+}
+
+func TestStratifiedSplitCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes [][]int
+		counts  []int
+	}{
+		{"按张数", append(repeatClasses(50, 0), repeatClasses(50, 1)...), []int{70, 23, 7}},
+		{"少数类", append(repeatClasses(90, 0), repeatClasses(10, 1)...), []int{13, 0, 87}},
+		{"多标签与无标注", append(append(repeatClasses(7, 0, 1), repeatClasses(5, 1)...), repeatClasses(3)...), []int{9, 5, 1}},
+	}
+	for _, tt := range tests {
+		got := StratifiedSplitCounts(tt.classes, tt.counts, 1)
+		have := make([]int, len(tt.counts))
+		for _, j := range got {
+			have[j]++
+		}
+		if !reflect.DeepEqual(have, tt.counts) {
+			t.Errorf("%s: 各划分张数 = %v, want %v", tt.name, have, tt.counts)
+		}
+	}
+}
+
+func TestGroupSplit(t *testing.T) {
+	tests := []struct {
+		keys   []string
+		ratios []float64
+		want   []int
+	}{
+		{[]string{"a", "a", "a", "b", "b", "c"}, []float64{0.5, 0.5, 0}, []int{0, 0, 0, 1, 1, 1}},
+		{[]string{"a", "b", "c", "d"}, []float64{0.75, 0.25, 0}, []int{0, 0, 0, 1}},
+		{[]string{"b", "a", "b", "a"}, []float64{0, 1, 0}, []int{1, 1, 1, 1}},
+		{nil, []float64{0.8, 0.2, 0}, []int{}},
+	}
+	for _, tt := range tests {
+		if got := GroupSplit(tt.keys, tt.ratios); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GroupSplit(%v, %v) = %v, want %v", tt.keys, tt.ratios, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package dataset
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// ==================== 切片 (大图切成重叠小块) ====================
+
+// ImageVariant 一张待输出的图片及其标注 (该图片的像素坐标)
+type ImageVariant struct {
+	Name   string // 输出文件名，不含扩展名
+	Img    image.Image
+	Boxes  []YoloBox
+	Src    []byte // 图片未改动时的源文件字节，供 SmartCompress 直接沿用
+	Format string
+}
+
+// TileOptions 切片参数
+type TileOptions struct {
+	Size       int     // 切片边长，0 表示不切片
+	Overlap    int     // 相邻切片重叠像素
+	MinVisible float64 // 框在切片内可见面积占比低于此值时丢弃
+}
+
+// tileStarts 单个方向上的切片起点，最后一块贴齐边缘，不产生不完整的切片
+func tileStarts(length, size, overlap int) []int {
+	if length <= size {
+		return []int{0}
+	}
+	step := size - overlap
+	var starts []int
+	for s := 0; s+size < length; s += step {
+		starts = append(starts, s)
+	}
+	return append(starts, length-size)
+}
+
+// TileImage 把图片切成重叠的切片，框裁剪到切片内并换算为切片坐标，
+// 文件名追加 _x{X}_y{Y} 记录切片在原图中的位置
+func TileImage(v ImageVariant, opt TileOptions) []ImageVariant {
+	b := v.Img.Bounds()
+	var tiles []ImageVariant
+	for _, y := range tileStarts(b.Dy(), opt.Size, opt.Overlap) {
+		for _, x := range tileStarts(b.Dx(), opt.Size, opt.Overlap) {
+			w, h := int(math.Min(float64(opt.Size), float64(b.Dx()))), int(math.Min(float64(opt.Size), float64(b.Dy())))
+			rect := image.Rect(x, y, x+w, y+h)
+			tile := image.NewRGBA(image.Rect(0, 0, w, h))
+			draw.Draw(tile, tile.Bounds(), v.Img, b.Min.Add(rect.Min), draw.Src)
+
+			boxes := CropBoxes(v.Boxes, rect, opt.MinVisible)
+			tiles = append(tiles, ImageVariant{
+				Name:   fmt.Sprintf("%s_x%d_y%d", v.Name, x, y),
+				Img:    tile,
+				Boxes:  boxes,
+				Format: v.Format,
+			})
+		}
+	}
+	return tiles
+}
+
+// CropBoxes 把框裁剪到 rect 内并换算为 rect 左上角为原点的坐标，丢弃可见比例不足的框
+func CropBoxes(boxes []YoloBox, rect image.Rectangle, minVisible float64) []YoloBox {
+	var out []YoloBox
+	x1, y1, x2, y2 := float64(rect.Min.X), float64(rect.Min.Y), float64(rect.Max.X), float64(rect.Max.Y)
+	for _, box := range boxes {
+		if clipped, ok := ClipBox(box, x1, y1, x2, y2, minVisible); ok {
+			out = append(out, clipped.MapPoints(func(x, y float64) (float64, float64) { return x - x1, y - y1 }))
+		}
+	}
+	return out
+}
+
+// ClipBox 把框裁剪到矩形内，可见面积占比低于 minVisible 或完全不可见时返回 false。
+// 有多边形时裁剪多边形 (Sutherland-Hodgman)，按多边形面积计算可见比例
+func ClipBox(b YoloBox, x1, y1, x2, y2, minVisible float64) (YoloBox, bool) {
+	if b.Poly != nil {
+		area := polygonArea(b.Poly)
+		clipped := clipPolygon(b.Poly, x1, y1, x2, y2)
+		if area <= 0 || len(clipped) < 3 {
+			return b, false
+		}
+		c := BoundingBox(b.Cls, clipped)
+		return c, polygonArea(clipped)/area >= minVisible && c.X2 > c.X1 && c.Y2 > c.Y1
+	}
+	area := (b.X2 - b.X1) * (b.Y2 - b.Y1)
+	c := YoloBox{
+		Cls: b.Cls,
+		X1:  math.Max(b.X1, x1), Y1: math.Max(b.Y1, y1),
+		X2: math.Min(b.X2, x2), Y2: math.Min(b.Y2, y2),
+	}
+	if area <= 0 || c.X2 <= c.X1 || c.Y2 <= c.Y1 {
+		return c, false
+	}
+	return c, (c.X2-c.X1)*(c.Y2-c.Y1)/area >= minVisible
+}
+
+// polygonArea 多边形面积 (鞋带公式)
+func polygonArea(pts [][2]float64) float64 {
+	a := 0.0
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		a += pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1]
+	}
+	return math.Abs(a) / 2
+}
+
+// clipPolygon 用轴对齐矩形裁剪多边形
+func clipPolygon(pts [][2]float64, x1, y1, x2, y2 float64) [][2]float64 {
+	type edge struct {
+		inside func(p [2]float64) bool
+		cross  func(a, b [2]float64) [2]float64
+	}
+	atX := func(x float64) func(a, b [2]float64) [2]float64 {
+		return func(a, b [2]float64) [2]float64 {
+			t := (x - a[0]) / (b[0] - a[0])
+			return [2]float64{x, a[1] + t*(b[1]-a[1])}
+		}
+	}
+	atY := func(y float64) func(a, b [2]float64) [2]float64 {
+		return func(a, b [2]float64) [2]float64 {
+			t := (y - a[1]) / (b[1] - a[1])
+			return [2]float64{a[0] + t*(b[0]-a[0]), y}
+		}
+	}
+	edges := []edge{
+		{func(p [2]float64) bool { return p[0] >= x1 }, atX(x1)},
+		{func(p [2]float64) bool { return p[0] <= x2 }, atX(x2)},
+		{func(p [2]float64) bool { return p[1] >= y1 }, atY(y1)},
+		{func(p [2]float64) bool { return p[1] <= y2 }, atY(y2)},
+	}
+	out := pts
+	for _, e := range edges {
+		in := out
+		out = nil
+		for i := range in {
+			cur, prev := in[i], in[(i+len(in)-1)%len(in)]
+			switch {
+			case e.inside(cur) && e.inside(prev):
+				out = append(out, cur)
+			case e.inside(cur):
+				out = append(out, e.cross(prev, cur), cur)
+			case e.inside(prev):
+				out = append(out, e.cross(prev, cur))
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+	}
+	return out
+}
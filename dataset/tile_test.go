@@ -0,0 +1,88 @@
+package dataset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTileStarts(t *testing.T) {
+	tests := []struct {
+		length, size, overlap int
+		want                  []int
+	}{
+		{100, 100, 0, []int{0}},
+		{50, 100, 10, []int{0}},
+		{200, 100, 0, []int{0, 100}},
+		{250, 100, 0, []int{0, 100, 150}}, // 最后一块贴齐边缘
+		{200, 100, 20, []int{0, 80, 100}},
+	}
+	for _, tt := range tests {
+		if got := tileStarts(tt.length, tt.size, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tileStarts(%d, %d, %d) = %v, want %v", tt.length, tt.size, tt.overlap, got, tt.want)
+		}
+	}
+}
+
+func TestClipBox(t *testing.T) {
+	square := YoloBox{Cls: 1, X1: 0, Y1: 0, X2: 10, Y2: 10}
+	triangle := YoloBox{Cls: 2, Poly: [][2]float64{{0, 0}, {10, 0}, {0, 10}}, X2: 10, Y2: 10}
+	tests := []struct {
+		name           string
+		box            YoloBox
+		x1, y1, x2, y2 float64
+		minVisible     float64
+		want           YoloBox // 只比较类别与外接框
+		ok             bool
+	}{
+		{"完全在内", square, -5, -5, 20, 20, 1, square, true},
+		{"可见一半", square, 5, 0, 20, 20, 0.5, YoloBox{Cls: 1, X1: 5, X2: 10, Y2: 10}, true},
+		{"可见比例不足", square, 5, 0, 20, 20, 0.6, YoloBox{}, false},
+		{"完全在外", square, 20, 20, 30, 30, 0, YoloBox{}, false},
+		{"只接触边缘", square, 10, 0, 20, 10, 0, YoloBox{}, false},
+		{"多边形按面积计算", triangle, 0, 0, 5, 10, 0.75, YoloBox{Cls: 2, X2: 5, Y2: 10}, true},
+		{"多边形可见比例不足", triangle, 0, 0, 5, 10, 0.8, YoloBox{}, false},
+		{"多边形完全在外", triangle, 6, 6, 20, 20, 0, YoloBox{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ClipBox(tt.box, tt.x1, tt.y1, tt.x2, tt.y2, tt.minVisible)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && (got.Cls != tt.want.Cls || got.X1 != tt.want.X1 || got.Y1 != tt.want.Y1 || got.X2 != tt.want.X2 || got.Y2 != tt.want.Y2) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClipPolygon(t *testing.T) {
+	square := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name           string
+		pts            [][2]float64
+		x1, y1, x2, y2 float64
+		area           float64
+		points         int
+	}{
+		{"完全在内", square, -1, -1, 11, 11, 100, 4},
+		{"裁掉一角", square, 5, 5, 20, 20, 25, 4},
+		{"裁成五边形", [][2]float64{{0, 0}, {10, 0}, {0, 10}}, 0, 0, 8, 8, 50 - 2 - 2, 5},
+		{"完全在外", square, 20, 20, 30, 30, 0, 0},
+	}
+	for _, tt := range tests {
+		got := clipPolygon(tt.pts, tt.x1, tt.y1, tt.x2, tt.y2)
+		if len(got) != tt.points {
+			t.Errorf("%s: %d 个顶点 %v, want %d", tt.name, len(got), got, tt.points)
+			continue
+		}
+		if len(got) > 0 && math.Abs(polygonArea(got)-tt.area) > 1e-9 {
+			t.Errorf("%s: 面积 = %v, want %v", tt.name, polygonArea(got), tt.area)
+		}
+		for _, p := range got {
+			if p[0] < tt.x1 || p[0] > tt.x2 || p[1] < tt.y1 || p[1] > tt.y2 {
+				t.Errorf("%s: 顶点 %v 在裁剪矩形外", tt.name, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ==================== 监视模式 ====================
+
+// DefaultDebounce 最后一次文件变化后等待多久再重建，标注软件连续保存时只触发一次
+const DefaultDebounce = 2 * time.Second
+
+// Watch 先以增量模式同步一次，之后监视数据源文件夹，图片或 JSON 变化后去抖动再增量重建。
+// 已有图片沿用 splits.json 中的划分；状态通过 EventStatus 报告。ctx 取消时返回 nil
+func Watch(ctx context.Context, o Options, debounce time.Duration) error {
+	o.Incremental, o.DryRun = true, false
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("无法监视文件夹: %v", err)
+	}
+	defer w.Close()
+	for _, d := range o.Sources {
+		if err := w.Add(d); err != nil {
+			return fmt.Errorf("无法监视 %s: %v", d, err)
+		}
+	}
+	status := func(msg string) { o.emit(Event{Kind: EventStatus, Message: msg}) }
+
+	// 构建失败时继续监视，等下一次变化再试
+	rebuild := func(reason string) {
+		status("构建中：" + reason)
+		res, err := Run(ctx, o)
+		now := time.Now().Format("15:04:05")
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			status(fmt.Sprintf("监视中 · %s 构建失败: %v", now, err))
+		case len(res.Errors) > 0:
+			status(fmt.Sprintf("监视中 · %s 已同步 %d 张 (重新转换 %d)，失败 %d 处", now, res.Images, res.Images-res.Skipped, len(res.Errors)))
+		default:
+			status(fmt.Sprintf("监视中 · %s 已同步 %d 张 (重新转换 %d)", now, res.Images, res.Images-res.Skipped))
+		}
+	}
+
+	rebuild("首次同步")
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	changed := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			status("已停止监视")
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if !watchRelevant(ev) {
+				continue
+			}
+			changed[ev.Name] = true
+			status(fmt.Sprintf("检测到 %d 个文件变化，等待保存完成...", len(changed)))
+			timer.Reset(debounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			o.emit(Event{Kind: EventLog, Message: "!!! 监视出错: " + err.Error()})
+		case <-timer.C:
+			n := len(changed)
+			clear(changed)
+			rebuild(fmt.Sprintf("%d 个文件变化", n))
+		}
+	}
+}
+
+// watchRelevant 只关心图片与 JSON 的增删改，忽略权限变化和编辑器的临时文件
+func watchRelevant(ev fsnotify.Event) bool {
+	if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Remove) && !ev.Has(fsnotify.Rename) {
+		return false
+	}
+	ext := filepath.Ext(ev.Name)
+	return IsImageExt(ext) || strings.EqualFold(ext, ".json")
+}
@@ -1,21 +1,17 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	"image/jpeg"
-	"io"
 	"math"
-	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -24,130 +20,17 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
 	_ "image/gif"
 	_ "image/png"
-)
-
-// ==================== 1. 基础工具函数 ====================
-
-// SmartCompress 智能压缩
-func SmartCompress(img image.Image, outPath string, maxKB int) error {
-	quality := 95
-	minQuality := 20
-	for quality >= minQuality {
-		buf := new(bytes.Buffer)
-		err := jpeg.Encode(buf, img, &jpeg.Options{Quality: quality})
-		if err != nil {
-			return err
-		}
-		if buf.Len()/1024 <= maxKB {
-			return os.WriteFile(outPath, buf.Bytes(), 0644)
-		}
-		quality -= 5
-	}
-	f, err := os.Create(outPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	return jpeg.Encode(f, img, &jpeg.Options{Quality: minQuality})
-}
 
-// DirectCopy 直接复制
-func DirectCopy(src, dst string) error {
-	sourceFileStat, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-	if !sourceFileStat.Mode().IsRegular() {
-		return fmt.Errorf("%s is not a regular file", src)
-	}
-	source, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
-	destination, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destination.Close()
-	_, err = io.Copy(destination, source)
-	return err
-}
-
-// ConvertJsonToYolo JSON转YOLO
-func ConvertJsonToYolo(jsonPath string, imgW, imgH int, classMap map[string]int) ([]string, error) {
-	fileBytes, err := os.ReadFile(jsonPath)
-	if err != nil {
-		return nil, err
-	}
-
-	type LabelMeShape struct {
-		Label  string      `json:"label"`
-		Points [][]float64 `json:"points"`
-	}
-	type LabelMeJSON struct {
-		Shapes []LabelMeShape `json:"shapes"`
-		Labels []struct {
-			Name string  `json:"name"`
-			X1   float64 `json:"x1"`
-			Y1   float64 `json:"y1"`
-			X2   float64 `json:"x2"`
-			Y2   float64 `json:"y2"`
-		} `json:"labels"`
-	}
-
-	var data LabelMeJSON
-	if err := json.Unmarshal(fileBytes, &data); err != nil {
-		return nil, err
-	}
-	var yoloLines []string
-
-	add := func(cls int, x1, y1, x2, y2 float64) {
-		w := x2 - x1
-		h := y2 - y1
-		cx := x1 + w/2.0
-		cy := y1 + h/2.0
-		line := fmt.Sprintf("%d %.6f %.6f %.6f %.6f", cls, cx/float64(imgW), cy/float64(imgH), w/float64(imgW), h/float64(imgH))
-		yoloLines = append(yoloLines, line)
-	}
-
-	for _, shape := range data.Shapes {
-		if id, ok := classMap[shape.Label]; ok && len(shape.Points) > 0 {
-			minX, minY := math.MaxFloat64, math.MaxFloat64
-			maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
-			for _, p := range shape.Points {
-				if len(p) >= 2 {
-					if p[0] < minX {
-						minX = p[0]
-					}
-					if p[0] > maxX {
-						maxX = p[0]
-					}
-					if p[1] < minY {
-						minY = p[1]
-					}
-					if p[1] > maxY {
-						maxY = p[1]
-					}
-				}
-			}
-			add(id, minX, minY, maxX, maxY)
-		}
-	}
-	for _, lbl := range data.Labels {
-		if id, ok := classMap[lbl.Name]; ok {
-			add(id, lbl.X1, lbl.Y1, lbl.X2, lbl.Y2)
-		}
-	}
-	return yoloLines, nil
-}
+	"yolotools/dataset"
+)
 
-// ==================== 2. 核心组件：交互式画布 (画框+删除) ====================
+// ==================== 1. 核心组件：交互式画布 (画框+删除) ====================
 
 type BoxData struct {
 	Cls  int
@@ -376,7 +259,30 @@ func (ii *InteractiveImage) removeLabelFromFile(targetRaw string) {
 	ii.onRefreshReq()
 }
 
-// ==================== 3. 预览窗口 ====================
+// selectedKey 下拉框当前选项对应的键名，未选择时取第一个
+func selectedKey(s *widget.Select, keys []string) string {
+	if i := s.SelectedIndex(); i >= 0 && i < len(keys) {
+		return keys[i]
+	}
+	return keys[0]
+}
+
+// selectKey 按键名选中下拉框中的模式，未知键名选第一个
+func selectKey(s *widget.Select, keys []string, key string) {
+	for i, k := range keys {
+		if k == key {
+			s.SetSelectedIndex(i)
+			return
+		}
+	}
+	s.SetSelectedIndex(0)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// ==================== 2. 预览窗口 ====================
 
 func ShowPreviewWindow(parent fyne.App, datasetDir string) {
 	win := parent.NewWindow("数据集审核 (拖拽画框 / 点击红框删除)")
@@ -397,12 +303,12 @@ func ShowPreviewWindow(parent fyne.App, datasetDir string) {
 	loadFiles := func() {
 		currentFiles = []string{}
 		currentSubsets = []string{}
-		for _, sub := range []string{"train", "val", "test"} {
+		// K 折模式的图片统一放在 images/all 下
+		for _, sub := range []string{"train", "val", "test", "all"} {
 			dir := filepath.Join(datasetDir, "images", sub)
 			files, _ := os.ReadDir(dir)
 			for _, f := range files {
-				ext := strings.ToLower(filepath.Ext(f.Name()))
-				if ext == ".jpg" || ext == ".png" || ext == ".bmp" || ext == ".jpeg" {
+				if dataset.IsImageExt(filepath.Ext(f.Name())) {
 					currentFiles = append(currentFiles, f.Name())
 					currentSubsets = append(currentSubsets, sub)
 				}
@@ -457,16 +363,17 @@ func ShowPreviewWindow(parent fyne.App, datasetDir string) {
 				}
 			}
 
-			interactiveWidget := NewInteractiveImage(win, img, labelPath, reloadCurrentItem)
-			interactiveWidget.LoadBoxes(boxList)
-			interactiveWidget.Resize(fyne.NewSize(origW, origH)) // 必须显式设置
-
-			scrollContainer.Content = interactiveWidget
-			scrollContainer.Refresh()
+			fyne.Do(func() {
+				interactiveWidget := NewInteractiveImage(win, img, labelPath, reloadCurrentItem)
+				interactiveWidget.LoadBoxes(boxList)
+				interactiveWidget.Resize(fyne.NewSize(origW, origH)) // 必须显式设置
 
-			statusLabel.SetText(fmt.Sprintf("%s [%.0fx%.0f] | 标注: %d | 操作: 拖拽新建, 点击删除",
-				filepath.Base(imgPath), origW, origH, len(boxList)))
+				scrollContainer.Content = interactiveWidget
+				scrollContainer.Refresh()
 
+				statusLabel.SetText(fmt.Sprintf("%s [%.0fx%.0f] | 标注: %d | 操作: 拖拽新建, 点击删除",
+					filepath.Base(imgPath), origW, origH, len(boxList)))
+			})
 		}(currentImgPath, currentLabelPath)
 	}
 
@@ -493,12 +400,105 @@ func ShowPreviewWindow(parent fyne.App, datasetDir string) {
 	win.Show()
 }
 
+// ==================== 3. 失败列表窗口 ====================
+
+// ShowErrorWindow 列出构建中失败的文件，点击表头排序，可导出为 CSV/JSON
+func ShowErrorWindow(parent fyne.App, errs []dataset.FileError) {
+	win := parent.NewWindow(fmt.Sprintf("失败列表 (%d)", len(errs)))
+	win.Resize(fyne.NewSize(1000, 500))
+
+	rows := append([]dataset.FileError(nil), errs...)
+	titles := []string{"文件", "阶段", "错误"}
+	cell := func(e dataset.FileError, col int) string {
+		switch col {
+		case 0:
+			return e.Path
+		case 1:
+			return dataset.StageNames[e.Stage]
+		}
+		return e.Err
+	}
+	sortCol, desc := 0, false
+
+	table := widget.NewTable(
+		func() (int, int) { return len(rows), len(titles) },
+		func() fyne.CanvasObject {
+			l := widget.NewLabel("")
+			l.Truncation = fyne.TextTruncateEllipsis
+			return l
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(cell(rows[id.Row], id.Col))
+		},
+	)
+	table.ShowHeaderRow = true
+	table.CreateHeader = func() fyne.CanvasObject { return widget.NewButton("", nil) }
+	table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		btn := o.(*widget.Button)
+		title := titles[id.Col]
+		if id.Col == sortCol {
+			if desc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		btn.SetText(title)
+		col := id.Col
+		btn.OnTapped = func() {
+			// 再次点击同一列时反向
+			if col == sortCol {
+				desc = !desc
+			} else {
+				sortCol, desc = col, false
+			}
+			sort.SliceStable(rows, func(i, j int) bool {
+				if desc {
+					return cell(rows[i], sortCol) > cell(rows[j], sortCol)
+				}
+				return cell(rows[i], sortCol) < cell(rows[j], sortCol)
+			})
+			table.Refresh()
+		}
+	}
+	table.SetColumnWidth(0, 420)
+	table.SetColumnWidth(1, 80)
+	table.SetColumnWidth(2, 460)
+
+	export := func(name string) {
+		dlg := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if err != nil || uc == nil {
+				return
+			}
+			path := uc.URI().Path()
+			uc.Close()
+			if err := dataset.SaveErrorReport(path, rows); err != nil {
+				dialog.ShowError(err, win)
+			}
+		}, win)
+		dlg.SetFileName(name)
+		dlg.Show()
+	}
+	btnCSV := widget.NewButtonWithIcon("导出 CSV", theme.DocumentSaveIcon(), func() { export("errors.csv") })
+	btnJSON := widget.NewButtonWithIcon("导出 JSON", theme.DocumentSaveIcon(), func() { export("errors.json") })
+
+	win.SetContent(container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), btnCSV, btnJSON), nil, nil, table))
+	win.Show()
+}
+
 // ==================== 4. 主程序 (含Windows崩溃修复) ====================
 
+const (
+	windowTitle        = "YOLO 数据集工具 (Windows E盘修复版)"
+	prefRecentProjects = "recentProjects" // 最近打开的项目文件，最新的在前
+	maxRecentProjects  = 8
+	maxLogLines        = 2000 // 日志窗口保留的最多行数
+)
+
 func main() {
 	// 使用 NewWithID 解决 Warning
 	myApp := app.NewWithID("yolo.tools.fix")
-	myWindow := myApp.NewWindow("YOLO 数据集工具 (Windows E盘修复版)")
+	myWindow := myApp.NewWindow(windowTitle)
 	myWindow.Resize(fyne.NewSize(1000, 700))
 
 	// 数据源
@@ -538,24 +538,145 @@ func main() {
 			}
 		}, myWindow)
 	})
+	btnOutArchive := widget.NewButton("压缩包", func() {
+		dlg := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if err == nil && uc != nil {
+				path := uc.URI().Path()
+				uc.Close()
+				os.Remove(path) // 对话框会预先创建空文件，实际写入由执行阶段完成
+				entryOut.SetText(path)
+			}
+		}, myWindow)
+		dlg.SetFileName("dataset.zip")
+		dlg.Show()
+	})
 	entryClasses := widget.NewEntry()
 	entryClasses.SetPlaceHolder("例如: hole, nut")
 	entryTrain := widget.NewEntry()
 	entryTrain.SetText("0.8")
+	entryTrain.SetPlaceHolder("如 0.8、80%、200张")
 	entryVal := widget.NewEntry()
 	entryVal.SetText("0.2")
+	entryTest := widget.NewEntry()
+	entryTest.SetPlaceHolder("留空=剩余")
+	entrySeed := widget.NewEntry()
+	entrySeed.SetPlaceHolder("留空则随机生成")
+	checkStratify := widget.NewCheck("按类别分层划分", nil)
+	entryFolds := widget.NewEntry()
+	entryFolds.SetText("0")
+	checkIncremental := widget.NewCheck("增量更新 (保留已有划分)", nil)
+	btnPreviewSplit := widget.NewButtonWithIcon("预览划分", theme.SearchIcon(), func() {
+		spec, err := dataset.ParseSplitSpec(entryTrain.Text, entryVal.Text, entryTest.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("划分设置无效: %v", err), myWindow)
+			return
+		}
+		folds, _ := strconv.Atoi(strings.TrimSpace(entryFolds.Text))
+		dirs := append([]string(nil), listData...)
+		go func() {
+			n := len(dataset.ScanSources(dirs, func(string) {}))
+			var msg string
+			if folds >= 2 {
+				msg = fmt.Sprintf("共 %d 张图片\nK 折: %s", n, dataset.DescribeCounts(dataset.FoldNames(folds), dataset.EqualCounts(n, folds)))
+			} else if counts, err := spec.Resolve(n); err != nil {
+				msg = fmt.Sprintf("共 %d 张图片\n%v", n, err)
+			} else {
+				msg = fmt.Sprintf("共 %d 张图片\n%s", n, dataset.DescribeCounts(dataset.SplitNames, counts))
+			}
+			fyne.Do(func() { dialog.ShowInformation("划分预览", msg, myWindow) })
+		}()
+	})
+	entryGroupRe := widget.NewEntry()
+	entryGroupRe.SetPlaceHolder(`文件名正则，如 ^(cam\d+)_`)
+	entryGroupRe.Disable()
+	selectGroup := widget.NewSelect(dataset.GroupModeNames, func(s string) {
+		if s == dataset.GroupModeNames[dataset.GroupRegex] {
+			entryGroupRe.Enable()
+		} else {
+			entryGroupRe.Disable()
+		}
+	})
+	selectGroup.SetSelected(dataset.GroupModeNames[dataset.GroupNone])
 	checkEnableProc := widget.NewCheck("启用压缩/转格式", nil)
 	checkEnableProc.SetChecked(true)
 	entryKB := widget.NewEntry()
 	entryKB.SetText("500")
+	checkVerbose := widget.NewCheck("逐张记录压缩结果", nil)
+	selectResize := widget.NewSelect(dataset.ResizeModeNames, nil)
+	selectResize.SetSelected(dataset.ResizeModeNames[dataset.ResizeNone])
+	entryResize := widget.NewEntry()
+	entryResize.SetText("640")
+	entryPad := widget.NewEntry()
+	entryPad.SetText("#727272")
+	selectAlpha := widget.NewSelect(dataset.AlphaModeNames, nil)
+	selectAlpha.SetSelected(dataset.AlphaModeNames[dataset.AlphaComposite])
+	entryAlphaBg := widget.NewEntry()
+	entryAlphaBg.SetText("#FFFFFF")
+	selectTiff := widget.NewSelect(dataset.TiffPageModeNames, nil)
+	selectTiff.SetSelected(dataset.TiffPageModeNames[dataset.TiffFirstPage])
+	checkIgnore := widget.NewCheck("忽略区域 (标签/填充色)", nil)
+	entryIgnoreLabels := widget.NewEntry()
+	entryIgnoreLabels.SetPlaceHolder("未标注区域的标签，如 ignore, crowd")
+	entryIgnoreFill := widget.NewEntry()
+	entryIgnoreFill.SetText("#727272")
+	checkRedact := widget.NewCheck("隐私脱敏 (标签/方式)", nil)
+	entryRedactLabels := widget.NewEntry()
+	entryRedactLabels.SetPlaceHolder("需脱敏的标签，如 face, plate")
+	selectRedact := widget.NewSelect(dataset.RedactModeNames, nil)
+	selectRedact.SetSelected(dataset.RedactModeNames[dataset.RedactBlur])
+	checkRedactDrop := widget.NewCheck("从标签中删除脱敏区域", nil)
+	entryRedactFill := widget.NewEntry()
+	entryRedactFill.SetText("#000000")
+	entryRedactFill.SetPlaceHolder("填充色 (纯色方式)")
+	checkTile := widget.NewCheck("切片 (边长/重叠/最小可见比例)", nil)
+	entryTileSize := widget.NewEntry()
+	entryTileSize.SetText("1280")
+	entryTileOverlap := widget.NewEntry()
+	entryTileOverlap.SetText("128")
+	entryTileVisible := widget.NewEntry()
+	entryTileVisible.SetText("0.3")
+	checkAug := widget.NewCheck("离线增强，仅训练集 (份数/颜色抖动/最小裁剪比例)", nil)
+	checkAugHFlip := widget.NewCheck("水平翻转", nil)
+	checkAugHFlip.SetChecked(true)
+	checkAugVFlip := widget.NewCheck("垂直翻转", nil)
+	checkAugRot := widget.NewCheck("90° 旋转", nil)
+	entryAugCopies := widget.NewEntry()
+	entryAugCopies.SetText("2")
+	entryAugJitter := widget.NewEntry()
+	entryAugJitter.SetText("0.2")
+	entryAugCrop := widget.NewEntry()
+	entryAugCrop.SetText("0.8")
+	selectOutMode := widget.NewSelect(dataset.OutputModeNames, nil)
+	selectOutMode.SetSelected(dataset.OutputModeNames[dataset.OutputCopy])
+	checkRelYaml := widget.NewCheck("data.yaml 使用相对路径", nil)
+	entryYamlExtra := widget.NewMultiLineEntry()
+	entryYamlExtra.SetPlaceHolder("附加键 (YAML)，例如: download: https://...")
+	entryYamlExtra.SetMinRowsVisible(2)
 
 	cardOutput := widget.NewCard("配置", "", container.NewVBox(
-		widget.NewLabel("输出目录:"), container.NewBorder(nil, nil, nil, btnOut, entryOut),
+		widget.NewLabel("输出目录 (或 .zip/.tar.gz):"), container.NewBorder(nil, nil, nil, container.NewHBox(btnOut, btnOutArchive), entryOut),
 		widget.NewLabel("类别:"), entryClasses,
+		checkRelYaml, entryYamlExtra,
 	))
 	cardParams := widget.NewCard("选项", "", container.NewVBox(
-		widget.NewLabel("比例 (Train/Val):"), container.NewGridWithColumns(2, entryTrain, entryVal),
-		checkEnableProc, container.NewBorder(nil, nil, widget.NewLabel("MaxKB:"), nil, entryKB),
+		widget.NewLabel("比例或张数 (Train/Val/Test，张数写 200张):"), container.NewGridWithColumns(3, entryTrain, entryVal, entryTest),
+		btnPreviewSplit,
+		container.NewBorder(nil, nil, widget.NewLabel("种子:"), nil, entrySeed),
+		checkStratify,
+		container.NewBorder(nil, nil, widget.NewLabel("K 折 (0 为关闭):"), nil, entryFolds),
+		checkIncremental,
+		container.NewBorder(nil, nil, widget.NewLabel("分组:"), nil, selectGroup), entryGroupRe,
+		container.NewGridWithColumns(2, checkEnableProc, checkVerbose), container.NewBorder(nil, nil, widget.NewLabel("MaxKB:"), nil, entryKB),
+		container.NewBorder(nil, nil, widget.NewLabel("尺寸:"), nil, selectResize),
+		container.NewGridWithColumns(2, entryResize, entryPad),
+		container.NewBorder(nil, nil, widget.NewLabel("透明:"), nil, container.NewGridWithColumns(2, selectAlpha, entryAlphaBg)),
+		container.NewBorder(nil, nil, widget.NewLabel("多页:"), nil, selectTiff),
+		checkIgnore, container.NewGridWithColumns(2, entryIgnoreLabels, entryIgnoreFill),
+		checkRedact, container.NewBorder(nil, nil, nil, selectRedact, entryRedactLabels), container.NewGridWithColumns(2, checkRedactDrop, entryRedactFill),
+		checkTile, container.NewGridWithColumns(3, entryTileSize, entryTileOverlap, entryTileVisible),
+		checkAug, container.NewGridWithColumns(3, checkAugHFlip, checkAugVFlip, checkAugRot),
+		container.NewGridWithColumns(3, entryAugCopies, entryAugJitter, entryAugCrop),
+		container.NewBorder(nil, nil, widget.NewLabel("输出方式:"), nil, selectOutMode),
 	))
 
 	// 运行
@@ -563,163 +684,392 @@ func main() {
 	logArea := widget.NewMultiLineEntry()
 	logArea.Disable()
 	logArea.TextStyle.Monospace = true
-	var logLock sync.Mutex
+	// 日志只追加，超过 maxLogLines 行时丢弃最早的四分之一，避免大数据集下越写越慢。
+	// logFunc 可在任意 goroutine 调用，界面更新经 fyne.Do 排队到主线程，logLines 也只在主线程读写
+	var logLines []string
 	logFunc := func(msg string) {
-		logLock.Lock()
-		defer logLock.Unlock()
-		logArea.SetText(logArea.Text + msg + "\n")
-		logArea.CursorRow = len(strings.Split(logArea.Text, "\n"))
-		logArea.Refresh()
-	}
-
-	// 【核心修复】btnRun 逻辑
-	btnRun := widget.NewButtonWithIcon("开始执行", theme.MediaPlayIcon(), func() {
-		// 基础校验
-		if len(listData) == 0 {
-			dialog.ShowError(fmt.Errorf("错误：未添加数据源"), myWindow)
+		fyne.Do(func() {
+			logLines = append(logLines, msg)
+			if len(logLines) > maxLogLines {
+				logLines = append(logLines[:0], logLines[len(logLines)-maxLogLines*3/4:]...)
+				logArea.SetText(strings.Join(logLines, "\n") + "\n")
+			} else {
+				logArea.Append(msg + "\n")
+			}
+			logArea.CursorRow = len(logLines)
+		})
+	}
+	// resetLog 清空日志，以 first 作为第一行；只在主线程调用
+	resetLog := func(first string) {
+		logLines = []string{first}
+		logArea.SetText(first + "\n")
+	}
+	// setProgress 可在任意 goroutine 调用
+	setProgress := func(done, total int) {
+		fyne.Do(func() { progressBar.SetValue(float64(done) / float64(total)) })
+	}
+
+	// 读取界面上的设置，数字输入框在这里先转成数值
+	collectSettings := func() (dataset.Settings, error) {
+		s := dataset.Settings{
+			Sources: append([]string(nil), listData...),
+			Output:  entryOut.Text, Classes: entryClasses.Text,
+			RelativeYaml: checkRelYaml.Checked, YamlExtra: entryYamlExtra.Text,
+			Train: entryTrain.Text, Val: entryVal.Text, Test: entryTest.Text, Seed: entrySeed.Text,
+			Stratify: checkStratify.Checked, Incremental: checkIncremental.Checked,
+			Group: selectedKey(selectGroup, dataset.GroupModeKeys), GroupRegex: entryGroupRe.Text,
+			Process: checkEnableProc.Checked, Verbose: checkVerbose.Checked,
+			Resize: selectedKey(selectResize, dataset.ResizeModeKeys), ResizeSize: entryResize.Text, Pad: entryPad.Text,
+			Alpha: selectedKey(selectAlpha, dataset.AlphaModeKeys), AlphaBg: entryAlphaBg.Text,
+			TiffPages: selectedKey(selectTiff, dataset.TiffPageModeKeys),
+			Tile:      checkTile.Checked,
+			Augment:   checkAug.Checked, AugHFlip: checkAugHFlip.Checked, AugVFlip: checkAugVFlip.Checked, AugRot90: checkAugRot.Checked,
+			Ignore: checkIgnore.Checked, IgnoreLabels: entryIgnoreLabels.Text, IgnoreFill: entryIgnoreFill.Text,
+			Redact: checkRedact.Checked, RedactLabels: entryRedactLabels.Text, RedactMode: selectedKey(selectRedact, dataset.RedactModeKeys), RedactDrop: checkRedactDrop.Checked, RedactFill: entryRedactFill.Text,
+			OutputMode: selectedKey(selectOutMode, dataset.OutputModeKeys),
+		}
+		var err error
+		if s.Folds, err = strconv.Atoi(strings.TrimSpace(entryFolds.Text)); err != nil {
+			return s, fmt.Errorf("K 折数必须为 0 或不小于 2 的整数")
+		}
+		if s.MaxKB, err = strconv.Atoi(strings.TrimSpace(entryKB.Text)); err != nil && s.Process {
+			return s, fmt.Errorf("MaxKB 必须是正整数")
+		}
+		// 未勾选的功能解析失败时保持 0，不报错
+		var errs [3]error
+		s.TileSize, errs[0] = strconv.Atoi(strings.TrimSpace(entryTileSize.Text))
+		s.TileOverlap, errs[1] = strconv.Atoi(strings.TrimSpace(entryTileOverlap.Text))
+		s.TileVisible, errs[2] = strconv.ParseFloat(strings.TrimSpace(entryTileVisible.Text), 64)
+		if s.Tile && (errs[0] != nil || errs[1] != nil || errs[2] != nil || s.TileSize <= 0) {
+			return s, fmt.Errorf("切片参数无效 (边长 > 重叠 >= 0，可见比例 0~1)")
+		}
+		s.AugCopies, errs[0] = strconv.Atoi(strings.TrimSpace(entryAugCopies.Text))
+		s.AugJitter, errs[1] = strconv.ParseFloat(strings.TrimSpace(entryAugJitter.Text), 64)
+		s.AugCrop, errs[2] = strconv.ParseFloat(strings.TrimSpace(entryAugCrop.Text), 64)
+		if s.Augment && (errs[0] != nil || errs[1] != nil || errs[2] != nil || s.AugCopies <= 0) {
+			return s, fmt.Errorf("增强参数无效 (份数 > 0，抖动与裁剪比例 0~1，裁剪 0 或 1 为不裁剪)")
+		}
+		return s, nil
+	}
+
+	// 把项目文件里的设置填回界面
+	applySettings := func(s dataset.Settings) {
+		listData = append([]string(nil), s.Sources...)
+		listWidget.Refresh()
+		entryOut.SetText(s.Output)
+		entryClasses.SetText(s.Classes)
+		checkRelYaml.SetChecked(s.RelativeYaml)
+		entryYamlExtra.SetText(s.YamlExtra)
+		entryTrain.SetText(s.Train)
+		entryVal.SetText(s.Val)
+		entryTest.SetText(s.Test)
+		entrySeed.SetText(s.Seed)
+		checkStratify.SetChecked(s.Stratify)
+		entryFolds.SetText(strconv.Itoa(s.Folds))
+		checkIncremental.SetChecked(s.Incremental)
+		entryGroupRe.SetText(s.GroupRegex)
+		selectKey(selectGroup, dataset.GroupModeKeys, s.Group)
+		checkEnableProc.SetChecked(s.Process)
+		entryKB.SetText(strconv.Itoa(s.MaxKB))
+		checkVerbose.SetChecked(s.Verbose)
+		selectKey(selectResize, dataset.ResizeModeKeys, s.Resize)
+		entryResize.SetText(s.ResizeSize)
+		entryPad.SetText(s.Pad)
+		selectKey(selectAlpha, dataset.AlphaModeKeys, s.Alpha)
+		entryAlphaBg.SetText(s.AlphaBg)
+		selectKey(selectTiff, dataset.TiffPageModeKeys, s.TiffPages)
+		checkTile.SetChecked(s.Tile)
+		entryTileSize.SetText(strconv.Itoa(s.TileSize))
+		entryTileOverlap.SetText(strconv.Itoa(s.TileOverlap))
+		entryTileVisible.SetText(formatFloat(s.TileVisible))
+		checkAug.SetChecked(s.Augment)
+		entryAugCopies.SetText(strconv.Itoa(s.AugCopies))
+		checkAugHFlip.SetChecked(s.AugHFlip)
+		checkAugVFlip.SetChecked(s.AugVFlip)
+		checkAugRot.SetChecked(s.AugRot90)
+		entryAugJitter.SetText(formatFloat(s.AugJitter))
+		entryAugCrop.SetText(formatFloat(s.AugCrop))
+		checkIgnore.SetChecked(s.Ignore)
+		entryIgnoreLabels.SetText(s.IgnoreLabels)
+		entryIgnoreFill.SetText(s.IgnoreFill)
+		checkRedact.SetChecked(s.Redact)
+		entryRedactLabels.SetText(s.RedactLabels)
+		selectKey(selectRedact, dataset.RedactModeKeys, s.RedactMode)
+		checkRedactDrop.SetChecked(s.RedactDrop)
+		entryRedactFill.SetText(s.RedactFill)
+		selectKey(selectOutMode, dataset.OutputModeKeys, s.OutputMode)
+	}
+
+	// 项目文件：打开 / 保存 / 最近打开 (记录在应用偏好里)
+	projectPath := ""
+	var refreshMenu func()
+	setProject := func(path string) {
+		projectPath = path
+		myWindow.SetTitle(windowTitle + " - " + filepath.Base(path))
+		recent := []string{path}
+		for _, p := range myApp.Preferences().StringList(prefRecentProjects) {
+			if p != path && len(recent) < maxRecentProjects {
+				recent = append(recent, p)
+			}
+		}
+		myApp.Preferences().SetStringList(prefRecentProjects, recent)
+		refreshMenu()
+	}
+	openProject := func(path string) {
+		s, err := dataset.LoadSettingsFile(path)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法打开项目：%v", err), myWindow)
 			return
 		}
-		if entryOut.Text == "" {
-			dialog.ShowError(fmt.Errorf("错误：未选择输出目录"), myWindow)
+		applySettings(s)
+		setProject(path)
+	}
+	saveProject := func(path string) {
+		s, err := collectSettings()
+		if err == nil {
+			err = dataset.SaveSettingsFile(path, s)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法保存项目：%v", err), myWindow)
 			return
 		}
-		if entryClasses.Text == "" {
-			dialog.ShowError(fmt.Errorf("错误：未填写类别"), myWindow)
+		setProject(path)
+	}
+	projectFilter := storage.NewExtensionFileFilter([]string{".yaml", ".yml", ".json"})
+	showOpenProject := func() {
+		dlg := dialog.NewFileOpen(func(uc fyne.URIReadCloser, err error) {
+			if err == nil && uc != nil {
+				path := uc.URI().Path()
+				uc.Close()
+				openProject(path)
+			}
+		}, myWindow)
+		dlg.SetFilter(projectFilter)
+		dlg.Show()
+	}
+	showSaveProject := func() {
+		dlg := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if err == nil && uc != nil {
+				path := uc.URI().Path()
+				uc.Close()
+				saveProject(path)
+			}
+		}, myWindow)
+		dlg.SetFilter(projectFilter)
+		dlg.SetFileName("project.yaml")
+		dlg.Show()
+	}
+	refreshMenu = func() {
+		var recentItems []*fyne.MenuItem
+		for _, p := range myApp.Preferences().StringList(prefRecentProjects) {
+			path := p
+			recentItems = append(recentItems, fyne.NewMenuItem(path, func() { openProject(path) }))
+		}
+		recentMenu := fyne.NewMenuItem("最近打开", nil)
+		if len(recentItems) > 0 {
+			recentMenu.ChildMenu = fyne.NewMenu("", recentItems...)
+		} else {
+			recentMenu.Disabled = true
+		}
+		myWindow.SetMainMenu(fyne.NewMainMenu(fyne.NewMenu("项目",
+			fyne.NewMenuItem("打开...", showOpenProject),
+			fyne.NewMenuItem("保存", func() {
+				if projectPath == "" {
+					showSaveProject()
+					return
+				}
+				saveProject(projectPath)
+			}),
+			fyne.NewMenuItem("另存为...", showSaveProject),
+			fyne.NewMenuItemSeparator(),
+			recentMenu,
+		)))
+	}
+	refreshMenu()
+
+	// 运行控制：执行期间禁用 [开始执行]，暂停/取消只在执行期间可用
+	var btnRun, btnDryRun, btnWatch, btnPause, btnCancel *widget.Button
+	var pauser *dataset.Pauser
+	var cancelRun context.CancelFunc
+	setRunning := func(running bool) {
+		btnPause.SetText("暂停")
+		btnPause.SetIcon(theme.MediaPauseIcon())
+		if running {
+			btnRun.Disable()
+			btnDryRun.Disable()
+			btnWatch.Disable()
+			btnPause.Enable()
+			btnCancel.Enable()
+		} else {
+			btnRun.Enable()
+			btnDryRun.Enable()
+			btnWatch.Enable()
+			btnPause.Disable()
+			btnCancel.Disable()
+		}
+	}
+	btnPause = widget.NewButtonWithIcon("暂停", theme.MediaPauseIcon(), func() {
+		if pauser.Paused() {
+			pauser.Resume()
+			btnPause.SetText("暂停")
+			btnPause.SetIcon(theme.MediaPauseIcon())
+			logFunc(">>> 继续")
+		} else {
+			pauser.Pause()
+			btnPause.SetText("继续")
+			btnPause.SetIcon(theme.MediaPlayIcon())
+			logFunc(">>> 已暂停，正在处理的图片完成后停止")
+		}
+	})
+	btnCancel = widget.NewButtonWithIcon("取消", theme.CancelIcon(), func() {
+		btnPause.Disable()
+		btnCancel.Disable()
+		logFunc(">>> 正在取消...")
+		cancelRun()
+	})
+	btnPause.Disable()
+	btnCancel.Disable()
+
+	// dryRun 为 true 时只统计并报告，不写入文件
+	startRun := func(dryRun bool) {
+		s, err := collectSettings()
+		var opts dataset.Options
+		if err == nil {
+			opts, err = s.Options()
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("错误：%v", err), myWindow)
 			return
 		}
+		// 种子留空时随机生成并回填，便于记录和复现
+		entrySeed.SetText(s.Seed)
 
 		progressBar.SetValue(0)
-		logArea.SetText("初始化中...\n")
-
-		// 获取参数
-		outDir := entryOut.Text
-		doProc := checkEnableProc.Checked
-		maxKB, _ := strconv.Atoi(entryKB.Text)
-		trainR, _ := strconv.ParseFloat(entryTrain.Text, 64)
-		valR, _ := strconv.ParseFloat(entryVal.Text, 64)
-		clsList := strings.Split(entryClasses.Text, ",")
-		clsMap := make(map[string]int)
-		for i, c := range clsList {
-			clsMap[strings.TrimSpace(c)] = i
+		resetLog("初始化中...")
+		ctx, cancel := context.WithCancel(context.Background())
+		pauser, cancelRun = dataset.NewPauser(), cancel
+		opts.Pause, opts.DryRun = pauser, dryRun
+		setRunning(true)
+
+		opts.OnEvent = func(e dataset.Event) {
+			switch e.Kind {
+			case dataset.EventLog:
+				logFunc(e.Message)
+			case dataset.EventProgress:
+				setProgress(e.Done, e.Total)
+			}
 		}
-
 		go func() {
+			defer fyne.Do(func() { setRunning(false) })
+			defer cancel()
 			// 【Panic 捕获】防止 Windows 静默崩溃
 			defer func() {
 				if r := recover(); r != nil {
-					dialog.ShowError(fmt.Errorf("程序发生异常:\n%v", r), myWindow)
+					fyne.Do(func() { dialog.ShowError(fmt.Errorf("程序发生异常:\n%v", r), myWindow) })
 				}
 			}()
 
-			logFunc(">>> 开始扫描...")
-			type FilePair struct{ ImgPath, JsonPath string }
-			var tasks []FilePair
-
-			for _, d := range listData {
-				files, err := os.ReadDir(d)
-				if err != nil {
-					logFunc("读取错误: " + d)
-					continue
-				}
-				for _, f := range files {
-					if !f.IsDir() {
-						ext := strings.ToLower(filepath.Ext(f.Name()))
-						if ext == ".jpg" || ext == ".png" || ext == ".bmp" || ext == ".jpeg" {
-							base := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
-							tasks = append(tasks, FilePair{filepath.Join(d, f.Name()), filepath.Join(d, base+".json")})
-						}
+			res, err := dataset.Run(ctx, opts)
+			// 结果只在主线程展示
+			fyne.Do(func() {
+				if errors.Is(err, context.Canceled) {
+					if dryRun {
+						dialog.ShowInformation("已取消", "试运行已停止", myWindow)
+					} else {
+						dialog.ShowInformation("已取消", "已停止处理并删除本次写入的文件", myWindow)
 					}
+					return
 				}
-			}
-
-			if len(tasks) == 0 {
-				logFunc("!!! 未找到图片")
-				dialog.ShowInformation("提示", "未找到图片，请检查路径", myWindow)
-				return
-			}
-
-			r := rand.New(rand.NewSource(time.Now().UnixNano()))
-			r.Shuffle(len(tasks), func(i, j int) { tasks[i], tasks[j] = tasks[j], tasks[i] })
-
-			// 创建目录 (带 Panic 检查)
-			for _, s := range []string{"train", "val", "test"} {
-				if err := os.MkdirAll(filepath.Join(outDir, "images", s), 0755); err != nil {
-					panic("无法创建目录: " + err.Error())
+				if err != nil {
+					logFunc("!!! " + err.Error())
+					dialog.ShowError(err, myWindow)
+					return
 				}
-				os.MkdirAll(filepath.Join(outDir, "labels", s), 0755)
-			}
-
-			total := len(tasks)
-			trainC := int(float64(total) * trainR)
-			valC := int(float64(total) * valR)
-			var wg sync.WaitGroup
-			limit := make(chan struct{}, 4)
-
-			for i, t := range tasks {
-				limit <- struct{}{}
-				wg.Add(1)
-				sub := "test"
-				if i < trainC {
-					sub = "train"
-				} else if i < trainC+valC {
-					sub = "val"
+				if res.Plan != nil {
+					dialog.ShowInformation("试运行完成", res.Plan.Summary()+"\n\n完整报告见日志", myWindow)
+					return
 				}
+				if len(res.Errors) > 0 {
+					ShowErrorWindow(myApp, res.Errors)
+					dialog.ShowInformation("完成 (有失败)", fmt.Sprintf("数据集处理完毕，但有 %d 处失败，详见失败列表", len(res.Errors)), myWindow)
+					return
+				}
+				dialog.ShowInformation("完成", "数据集处理完毕", myWindow)
+			})
+		}()
+	}
+	btnRun = widget.NewButtonWithIcon("开始执行", theme.MediaPlayIcon(), func() { startRun(false) })
+	btnDryRun = widget.NewButtonWithIcon("试运行", theme.SearchIcon(), func() { startRun(true) })
+
+	// 监视模式：源文件变化时自动增量重建，期间不能再手动执行
+	statusLabel := widget.NewLabel("")
+	statusLabel.Hide()
+	// stopWatch 只在主线程读写；监视结束 (停止或出错) 后由后台 goroutine 经 fyne.Do 清空
+	var stopWatch context.CancelFunc
+	setWatching := func(watching bool) {
+		if watching {
+			btnRun.Disable()
+			btnDryRun.Disable()
+			btnWatch.SetText("停止监视")
+			btnWatch.SetIcon(theme.MediaStopIcon())
+			statusLabel.Show()
+		} else {
+			btnRun.Enable()
+			btnDryRun.Enable()
+			btnWatch.SetText("监视")
+			btnWatch.SetIcon(theme.ViewRefreshIcon())
+			statusLabel.Hide()
+		}
+	}
+	btnWatch = widget.NewButtonWithIcon("监视", theme.ViewRefreshIcon(), func() {
+		if stopWatch != nil {
+			// 等正在进行的重建收尾后再恢复按钮，避免新旧两次监视同时运行
+			stopWatch()
+			btnWatch.Disable()
+			statusLabel.SetText("正在停止...")
+			return
+		}
+		s, err := collectSettings()
+		var opts dataset.Options
+		if err == nil {
+			opts, err = s.Options()
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("错误：%v", err), myWindow)
+			return
+		}
+		// 各次重建共用同一个种子，新图片的划分才可复现
+		entrySeed.SetText(s.Seed)
+		checkIncremental.SetChecked(true)
 
-				go func(idx int, task FilePair, subset string) {
-					defer wg.Done()
-					defer func() { <-limit }()
-
-					// 单个任务容错
-					defer func() { recover() }()
-
-					base := strings.TrimSuffix(filepath.Base(task.ImgPath), filepath.Ext(task.ImgPath))
-					var imgW, imgH int
-
-					if doProc {
-						f, err := os.Open(task.ImgPath)
-						if err == nil {
-							img, _, err := image.Decode(f)
-							f.Close()
-							if err == nil {
-								imgW, imgH = img.Bounds().Dx(), img.Bounds().Dy()
-								SmartCompress(img, filepath.Join(outDir, "images", subset, base+".jpg"), maxKB)
-							}
-						}
-					} else {
-						f, err := os.Open(task.ImgPath)
-						if err == nil {
-							cfg, _, err := image.DecodeConfig(f)
-							f.Close()
-							if err == nil {
-								imgW, imgH = cfg.Width, cfg.Height
-								DirectCopy(task.ImgPath, filepath.Join(outDir, "images", subset, base+filepath.Ext(task.ImgPath)))
-							}
-						}
-					}
-
-					if _, err := os.Stat(task.JsonPath); err == nil && imgW > 0 {
-						lines, err := ConvertJsonToYolo(task.JsonPath, imgW, imgH, clsMap)
-						if err == nil {
-							os.WriteFile(filepath.Join(outDir, "labels", subset, base+".txt"), []byte(strings.Join(lines, "\n")), 0644)
-						}
-					}
-					progressBar.SetValue(float64(idx+1) / float64(total))
-				}(i, t, sub)
-			}
-			wg.Wait()
-
-			yaml := fmt.Sprintf("path: %s\ntrain: images/train\nval: images/val\ntest: images/test\nnames:\n", outDir)
-			invMap := make(map[int]string)
-			for k, v := range clsMap {
-				invMap[v] = k
-			}
-			for i := 0; i < len(invMap); i++ {
-				yaml += fmt.Sprintf("  %d: %s\n", i, invMap[i])
+		progressBar.SetValue(0)
+		resetLog(">>> 监视模式：" + strings.Join(s.Sources, ", "))
+		statusLabel.SetText("准备中...")
+		ctx, cancel := context.WithCancel(context.Background())
+		stopWatch = cancel
+		setWatching(true)
+		opts.OnEvent = func(e dataset.Event) {
+			switch e.Kind {
+			case dataset.EventLog:
+				logFunc(e.Message)
+			case dataset.EventProgress:
+				setProgress(e.Done, e.Total)
+			case dataset.EventStatus:
+				fyne.Do(func() { statusLabel.SetText(e.Message) })
 			}
-			os.WriteFile(filepath.Join(outDir, "data.yaml"), []byte(yaml), 0644)
-
-			logFunc(">>> 完成！")
-			dialog.ShowInformation("完成", "数据集处理完毕", myWindow)
+		}
+		go func() {
+			defer cancel()
+			err := dataset.Watch(ctx, opts, dataset.DefaultDebounce)
+			fyne.Do(func() {
+				stopWatch = nil
+				setWatching(false)
+				btnWatch.Enable()
+				if err != nil {
+					logFunc("!!! " + err.Error())
+					dialog.ShowError(err, myWindow)
+				}
+			})
 		}()
 	})
 
@@ -728,12 +1078,53 @@ func main() {
 			dialog.ShowInformation("提示", "请先选择输出目录", myWindow)
 			return
 		}
+		if dataset.IsArchivePath(entryOut.Text) {
+			dialog.ShowInformation("提示", "压缩包输出需解压后再审核", myWindow)
+			return
+		}
 		ShowPreviewWindow(myApp, entryOut.Text)
 	})
 
+	btnVerify := widget.NewButtonWithIcon("校验清单", theme.ConfirmIcon(), func() {
+		if entryOut.Text == "" || dataset.IsArchivePath(entryOut.Text) {
+			dialog.ShowInformation("提示", "请先选择要校验的输出目录", myWindow)
+			return
+		}
+		root := entryOut.Text
+		progressBar.SetValue(0)
+		resetLog(">>> 校验清单: " + root)
+		go func() {
+			report, err := dataset.VerifyManifest(root, setProgress)
+			if err != nil {
+				logFunc("校验失败: " + err.Error())
+				fyne.Do(func() { dialog.ShowError(err, myWindow) })
+				return
+			}
+			for _, p := range report.Missing {
+				logFunc("[缺失] " + p)
+			}
+			for _, p := range report.Modified {
+				logFunc("[已修改] " + p)
+			}
+			for _, p := range report.Extra {
+				logFunc("[多余] " + p)
+			}
+			summary := fmt.Sprintf("已校验 %d 个文件：缺失 %d，已修改 %d，多余 %d",
+				report.Checked, len(report.Missing), len(report.Modified), len(report.Extra))
+			logFunc(">>> " + summary)
+			fyne.Do(func() {
+				if report.OK() {
+					dialog.ShowInformation("校验通过", summary, myWindow)
+				} else {
+					dialog.ShowInformation("校验未通过", summary, myWindow)
+				}
+			})
+		}()
+	})
+
 	rightPane := container.NewBorder(
 		container.NewPadded(container.NewGridWithColumns(2, cardOutput, cardParams)),
-		container.NewPadded(container.NewVBox(progressBar, container.NewHBox(btnRun, layout.NewSpacer(), btnPreview))),
+		container.NewPadded(container.NewVBox(progressBar, statusLabel, container.NewHBox(btnRun, btnDryRun, btnWatch, btnPause, btnCancel, layout.NewSpacer(), btnVerify, btnPreview))),
 		nil, nil, container.NewPadded(logArea),
 	)
 